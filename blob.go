@@ -0,0 +1,328 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/option"
+)
+
+// mediaTypeOctetStream is the default media type of pushed blobs.
+const mediaTypeOctetStream = "application/octet-stream"
+
+type FetchBlobOptions struct {
+	option.Common
+	option.Target
+
+	// Output receives the blob. The blob is written to OutputPath if not set.
+	Output     io.Writer
+	OutputPath string
+}
+
+// FetchBlob fetches the blob referenced by digest in opts and verifies its
+// digest while writing it to the output.
+func FetchBlob(ctx context.Context, opts FetchBlobOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	if opts.Output == nil && opts.OutputPath == "" {
+		return ocispec.Descriptor{}, errors.New("output or output path required")
+	}
+	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	dgst, err := blobDigest(opts.Target)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	var desc ocispec.Descriptor
+	var rc io.ReadCloser
+	if repo, ok := target.(registry.Repository); ok {
+		desc, rc, err = repo.Blobs().FetchReference(ctx, opts.Reference)
+	} else {
+		// the size is unknown until read
+		desc = ocispec.Descriptor{MediaType: mediaTypeOctetStream, Digest: dgst, Size: -1}
+		rc, err = target.Fetch(ctx, desc)
+	}
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer rc.Close()
+
+	out := opts.Output
+	var file *os.File
+	renamed := false
+	if out == nil {
+		if file, err = os.CreateTemp(filepath.Dir(opts.OutputPath), ".blob-*"); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		defer func() {
+			// the file is renamed to OutputPath only once verified
+			if !renamed {
+				file.Close()
+				os.Remove(file.Name())
+			}
+		}()
+		out = file
+	}
+	verifier := dgst.Verifier()
+	n, err := io.Copy(io.MultiWriter(out, verifier), rc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if desc.Size >= 0 && n != desc.Size {
+		return ocispec.Descriptor{}, fmt.Errorf("%s: %w: expect size %d, got %d", dgst, content.ErrMismatchedDigest, desc.Size, n)
+	}
+	if !verifier.Verified() {
+		return ocispec.Descriptor{}, fmt.Errorf("%s: %w", dgst, content.ErrMismatchedDigest)
+	}
+	desc.Size = n
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		if err := os.Rename(file.Name(), opts.OutputPath); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		renamed = true
+	}
+	return desc, nil
+}
+
+type PushBlobOptions struct {
+	option.Common
+	option.Target
+
+	// Content is the blob to push. The file at FilePath is pushed if not set.
+	Content  io.Reader
+	FilePath string
+	// MediaType is the media type of the blob. `application/octet-stream` is
+	// used if not set.
+	MediaType string
+	// MountFrom lists the repositories in the same registry to mount the blob
+	// from instead of uploading it, if any of them holds the blob.
+	MountFrom []string
+}
+
+// PushBlob pushes the blob of opts to the target. The size and digest of the
+// blob are calculated ahead of pushing, so Content is hashed while it is
+// buffered to a temporary file first. The reference in opts, if any, should be
+// the digest of the blob.
+func PushBlob(ctx context.Context, opts PushBlobOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	ctx = display.WithOperation(ctx, display.OperationPush)
+	handler, done := opts.Events()
+	defer done()
+	if err := ensureNoVersionConstraint(opts.Target); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	mediaType := opts.MediaType
+	if mediaType == "" {
+		mediaType = mediaTypeOctetStream
+	}
+	var desc ocispec.Descriptor
+	path := opts.FilePath
+	if opts.Content != nil {
+		// buffer the content to push it once its digest is known, hashing it
+		// while buffering
+		tmp, err := os.CreateTemp("", "oras-blob-*")
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		defer os.Remove(tmp.Name())
+		digester := digest.Canonical.Digester()
+		size, err := io.Copy(tmp, io.TeeReader(opts.Content, digester.Hash()))
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		path = tmp.Name()
+		desc = ocispec.Descriptor{
+			MediaType: mediaType,
+			Digest:    digester.Digest(),
+			Size:      size,
+		}
+	} else if path == "" {
+		return ocispec.Descriptor{}, errors.New("blob content or file path required")
+	} else {
+		var err error
+		if desc, err = fileDescriptor(path, mediaType); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	dst, err := opts.NewTarget(opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if opts.Reference != "" {
+		if dgst, err := digest.Parse(opts.Reference); err != nil || dgst != desc.Digest {
+			return ocispec.Descriptor{}, fmt.Errorf("reference %q mismatches the blob digest %s", opts.Reference, desc.Digest)
+		}
+	}
+	ref := opts.DisplayReference(desc.Digest)
+	exists, err := dst.Exists(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if exists {
+		if err := handler.OnStatus(ctx, "Exists", ref); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		return desc, handler.OnStatus(ctx, "Digest:", desc.Digest.String())
+	}
+
+	open := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	from, err := findMountSource(ctx, opts, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if mounter, ok := dst.(registry.Mounter); ok && from != "" {
+		err = mounter.Mount(ctx, desc, from, open)
+	} else {
+		var rc io.ReadCloser
+		if rc, err = open(); err == nil {
+			err = dst.Push(ctx, desc, rc)
+			rc.Close()
+		}
+	}
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if from != "" {
+		err = handler.OnStatus(ctx, "Mounted", ref+" from "+from)
+	} else {
+		err = handler.OnStatus(ctx, "Pushed", ref)
+	}
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, handler.OnStatus(ctx, "Digest:", desc.Digest.String())
+}
+
+// findMountSource returns the first repository in opts.MountFrom holding the
+// blob, or an empty string if not found.
+func findMountSource(ctx context.Context, opts PushBlobOptions, desc ocispec.Descriptor) (string, error) {
+	if opts.Type != option.TargetTypeRemote || len(opts.MountFrom) == 0 {
+		return "", nil
+	}
+	_, logger := opts.WithContext(ctx)
+	ref, err := registry.ParseReference(opts.Path)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range opts.MountFrom {
+		repo, err := opts.NewRepository(ref.Registry+"/"+name, opts.Common, logger)
+		if err != nil {
+			return "", err
+		}
+		exists, err := repo.Blobs().Exists(ctx, desc)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+type DeleteBlobOptions struct {
+	option.Common
+	option.Target
+
+	// Confirm is called with the blob before deleting. The blob is not deleted
+	// if it returns false.
+	Confirm func(ctx context.Context, desc ocispec.Descriptor) (bool, error)
+}
+
+// DeleteBlob deletes the blob referenced by digest in opts from the registry.
+// It returns false if the deletion is not confirmed.
+func DeleteBlob(ctx context.Context, opts DeleteBlobOptions) (bool, error) {
+	ctx, logger := opts.WithContext(ctx)
+	handler, done := opts.Events()
+	defer done()
+	// reject other targets before building one, which creates missing OCI
+	// image layouts, while building a remote target has no side effects
+	if opts.Type != option.TargetTypeRemote {
+		return false, fmt.Errorf("deleting blobs from %s is not supported", opts.Type)
+	}
+	target, err := opts.NewTarget(opts.Common, logger)
+	if err != nil {
+		return false, err
+	}
+	if _, err := blobDigest(opts.Target); err != nil {
+		return false, err
+	}
+	repo, ok := target.(registry.Repository)
+	if !ok {
+		return false, fmt.Errorf("deleting blobs from %s is not supported", opts.Type)
+	}
+	desc, err := repo.Blobs().Resolve(ctx, opts.Reference)
+	if err != nil {
+		return false, err
+	}
+	if opts.Confirm != nil {
+		if confirmed, err := opts.Confirm(ctx, desc); err != nil || !confirmed {
+			return false, err
+		}
+	}
+	if err := repo.Blobs().Delete(ctx, desc); err != nil {
+		return false, err
+	}
+	return true, handler.OnStatus(ctx, "Deleted", opts.DisplayReference(desc.Digest))
+}
+
+// blobDigest returns the digest referenced by the target.
+func blobDigest(opts option.Target) (digest.Digest, error) {
+	if err := ensureNoVersionConstraint(opts); err != nil {
+		return "", err
+	}
+	dgst, err := digest.Parse(opts.Reference)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a blob reference: a digest is required: %w", opts.RawReference, err)
+	}
+	return dgst, nil
+}
+
+// ensureNoVersionConstraint returns an error if the target selects a tag by
+// version constraint, as blobs are referenced by digest only.
+func ensureNoVersionConstraint(opts option.Target) error {
+	if opts.VersionConstraint != "" {
+		return fmt.Errorf("version constraint %q cannot be used with blobs: a digest is required", opts.VersionConstraint)
+	}
+	return nil
+}
+
+// fileDescriptor calculates the descriptor of the file at FilePath.
+func fileDescriptor(path, mediaType string) (ocispec.Descriptor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer file.Close()
+	digester := digest.Canonical.Digester()
+	size, err := io.Copy(digester.Hash(), file)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digester.Digest(),
+		Size:      size,
+	}, nil
+}
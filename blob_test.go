@@ -0,0 +1,138 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestBlob(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	newTarget := func(repo, ref string) option.Target {
+		target := option.Target{
+			Remote:       option.NewRemote(true, "", ""),
+			Type:         option.TargetTypeRemote,
+			RawReference: reg.Reference(repo, ref),
+		}
+		return target
+	}
+
+	pushOpts := PushBlobOptions{Content: strings.NewReader("hello"), MediaType: "text/plain"}
+	pushOpts.Target = newTarget("a", "")
+	desc, err := PushBlob(ctx, pushOpts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), desc.Size)
+	assert.Equal(t, "text/plain", desc.MediaType)
+	got, ok := reg.Blob("a", desc.Digest)
+	assert.True(t, ok)
+	assert.Equal(t, "hello", string(got))
+
+	var buf bytes.Buffer
+	fetchOpts := FetchBlobOptions{Output: &buf}
+	fetchOpts.Target = newTarget("a", desc.Digest.String())
+	fetched, err := FetchBlob(ctx, fetchOpts)
+	require.NoError(t, err)
+	assert.Equal(t, desc.Digest, fetched.Digest)
+	assert.Equal(t, "hello", buf.String())
+
+	path := filepath.Join(t.TempDir(), "blob")
+	fetchOpts = FetchBlobOptions{OutputPath: path}
+	fetchOpts.Target = newTarget("a", desc.Digest.String())
+	_, err = FetchBlob(ctx, fetchOpts)
+	require.NoError(t, err)
+	got, err = os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(got))
+	// no temporary file is left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	fetchOpts.Target = newTarget("a", "v1")
+	_, err = FetchBlob(ctx, fetchOpts)
+	assert.NotNil(t, err)
+
+	// mount from the repository holding the blob
+	blobPath := filepath.Join(t.TempDir(), "hello.txt")
+	require.NoError(t, os.WriteFile(blobPath, []byte("hello"), 0o600))
+	pushOpts = PushBlobOptions{FilePath: blobPath, MountFrom: []string{"missing", "a"}}
+	pushOpts.Target = newTarget("b", desc.Digest.String())
+	reg.ResetRequests()
+	_, err = PushBlob(ctx, pushOpts)
+	require.NoError(t, err)
+	_, ok = reg.Blob("b", desc.Digest)
+	assert.True(t, ok)
+	for _, req := range reg.Requests() {
+		assert.NotContains(t, req, http.MethodPut+" /v2/b/blobs/uploads/")
+	}
+
+	pushOpts.Target = newTarget("c", "sha256:"+strings.Repeat("0", 64))
+	_, err = PushBlob(ctx, pushOpts)
+	assert.NotNil(t, err)
+
+	deleteOpts := DeleteBlobOptions{}
+	deleteOpts.Target = newTarget("b", desc.Digest.String())
+	deleteOpts.Confirm = func(context.Context, ocispec.Descriptor) (bool, error) {
+		return false, nil
+	}
+	deleted, err := DeleteBlob(ctx, deleteOpts)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleteOpts.Confirm = nil
+	deleted, err = DeleteBlob(ctx, deleteOpts)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	_, ok = reg.Blob("b", desc.Digest)
+	assert.False(t, ok)
+}
+
+func TestBlob_ociLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "layout")
+	pushOpts := PushBlobOptions{Content: strings.NewReader("hello")}
+	pushOpts.Type = option.TargetTypeOCILayout
+	pushOpts.RawReference = dir
+	desc, err := PushBlob(ctx, pushOpts)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fetchOpts := FetchBlobOptions{Output: &buf}
+	fetchOpts.Type = option.TargetTypeOCILayout
+	fetchOpts.RawReference = dir + "@" + desc.Digest.String()
+	fetched, err := FetchBlob(ctx, fetchOpts)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), fetched.Size)
+	assert.Equal(t, "hello", buf.String())
+
+	// corrupted blob
+	blobPath := filepath.Join(dir, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+	require.NoError(t, os.WriteFile(blobPath, []byte("world"), 0o600))
+	_, err = FetchBlob(ctx, fetchOpts)
+	assert.NotNil(t, err)
+
+	deleteOpts := DeleteBlobOptions{}
+	deleteOpts.Type = option.TargetTypeOCILayout
+	deleteOpts.RawReference = fetchOpts.RawReference
+	_, err = DeleteBlob(ctx, deleteOpts)
+	assert.NotNil(t, err)
+
+	// no layout is created for blobs failed to be deleted
+	missing := filepath.Join(t.TempDir(), "missing")
+	deleteOpts.RawReference = missing + "@" + desc.Digest.String()
+	_, err = DeleteBlob(ctx, deleteOpts)
+	assert.NotNil(t, err)
+	assert.NoDirExists(t, missing)
+}
@@ -0,0 +1,586 @@
+// Package cache manages the local cache of fetched content shared by
+// concurrent processes.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// ErrCorrupted is returned when a cached blob mismatches its digest.
+var ErrCorrupted = errors.New("corrupted cache entry")
+
+// lockFileName is the name of the lock file under the cache root.
+const lockFileName = ".lock"
+
+// blobsDir is the directory of the blobs in an OCI image layout.
+const blobsDir = "blobs"
+
+// Options limits the content kept in the cache.
+type Options struct {
+	// MaxSize is the maximum total size of the cached blobs in bytes. The
+	// least recently used blobs are evicted when exceeded. There is no limit
+	// if it is less than or equal to 0.
+	MaxSize int64
+	// MaxAge evicts the blobs not used within the duration. There is no limit
+	// if it is less than or equal to 0.
+	MaxAge time.Duration
+}
+
+// Stats is the statistics of the cache.
+type Stats struct {
+	// Entries is the number of cached blobs.
+	Entries int
+	// Bytes is the total size of the cached blobs.
+	Bytes int64
+	// Tags is the number of cached tags and referrer lists.
+	Tags int
+	// Hits and Misses count the fetches served from the cache or not by the
+	// managers of the root in this process.
+	Hits   int64
+	Misses int64
+}
+
+// PruneResult is the blobs and tags evicted by pruning.
+type PruneResult struct {
+	Entries int
+	Bytes   int64
+	// Tags is the number of evicted tags and referrer lists.
+	Tags int
+}
+
+// Manager is a content.Storage caching blobs in an OCI image layout under its
+// root. The last access time of a blob is tracked by its modification time,
+// so that processes sharing the root evict blobs in the same order.
+//
+// Fetching and pushing blobs hold a shared lock on the root, while pruning and
+// verifying hold an exclusive one.
+type Manager struct {
+	root    string
+	opts    Options
+	storage *oci.Storage
+	state   *rootState
+}
+
+// rootState is shared by the managers of the same root in a process.
+type rootState struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	// sizeLock guards size and sized.
+	sizeLock sync.Mutex
+	// size is the total size of the cached blobs, tracked since sized.
+	size  int64
+	sized bool
+}
+
+// rootStates maps the absolute cache roots to their states.
+var rootStates sync.Map
+
+// New returns a cache manager of the root directory. Managers of the same root
+// share their statistics and the tracked size of the cache.
+func New(root string, opts Options) (*Manager, error) {
+	storage, err := oci.NewStorage(root)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	state, _ := rootStates.LoadOrStore(abs, &rootState{})
+	return &Manager{
+		root:    root,
+		opts:    opts,
+		storage: storage,
+		state:   state.(*rootState),
+	}, nil
+}
+
+// Root returns the root directory of the cache.
+func (m *Manager) Root() string {
+	return m.root
+}
+
+// Fetch fetches the cached blob and marks it as accessed. The blob is
+// verified against the descriptor while being read: blobs of unexpected sizes
+// are evicted and reported as not found, so that they are fetched from the
+// origin again, while reading a blob mismatching its digest fails with
+// ErrCorrupted once it is read in full, and the blob is evicted. The returned
+// reader seeks to the start, so that the blob can be verified before use.
+func (m *Manager) Fetch(_ context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	if err := target.Digest.Validate(); err != nil {
+		return nil, err
+	}
+	unlock, err := m.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	path := m.blobPath(target.Digest)
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			m.state.misses.Add(1)
+			return nil, fmt.Errorf("%s: %s: %w", target.Digest, target.MediaType, errdef.ErrNotFound)
+		}
+		return nil, err
+	}
+	// descriptors without sizes are verified by digests only
+	if target.Size > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if info.Size() != target.Size {
+			file.Close()
+			m.state.misses.Add(1)
+			if err := m.remove(path); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%s: %w: expect size %d, got %d: %w",
+				target.Digest, ErrCorrupted, target.Size, info.Size(), errdef.ErrNotFound)
+		}
+	}
+	m.state.hits.Add(1)
+	now := time.Now()
+	// failing to track the access only affects the eviction order
+	_ = os.Chtimes(path, now, now)
+	return &verifyingReader{
+		file:     file,
+		digest:   target.Digest,
+		size:     target.Size,
+		verifier: target.Digest.Verifier(),
+		evict: func() {
+			if unlock, err := m.lock(false); err == nil {
+				defer unlock()
+				// a blob failed to be evicted is detected again on next read
+				_ = m.remove(path)
+			}
+		},
+	}, nil
+}
+
+// remove removes the cached blob at path. The caller must hold a lock.
+func (m *Manager) remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	m.state.resetSize()
+	return nil
+}
+
+// verifyingReader verifies the cached blob against its digest once it is read
+// in full, and evicts it on mismatch.
+type verifyingReader struct {
+	file     *os.File
+	digest   digest.Digest
+	size     int64
+	read     int64
+	verifier digest.Verifier
+	evict    func()
+	err      error
+}
+
+// Read reads the blob. ErrCorrupted is returned with the last bytes of the
+// blob, or at EOF if its size is unknown, if the blob mismatches its digest.
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.file.Read(p)
+	r.read += int64(n)
+	_, _ = r.verifier.Write(p[:n])
+	// verify before EOF as readers limited to the size may stop reading there
+	if (err == io.EOF || r.read == r.size) && !r.verifier.Verified() {
+		r.evict()
+		err = fmt.Errorf("%s: %w: digest mismatch", r.digest, ErrCorrupted)
+	}
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// Seek seeks to the start of the blob to read and verify it again. Seeking
+// elsewhere is not supported, nor is seeking a blob found corrupted.
+func (r *verifyingReader) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, fmt.Errorf("%s: seeking to offset %d from %d: %w", r.digest, offset, whence, errdef.ErrUnsupported)
+	}
+	if r.err != nil && r.err != io.EOF {
+		return 0, r.err
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r.read, r.verifier, r.err = 0, r.digest.Verifier(), nil
+	return 0, nil
+}
+
+// Close closes the blob.
+func (r *verifyingReader) Close() error {
+	return r.file.Close()
+}
+
+// Push caches the blob and prunes the cache if it exceeds MaxSize.
+func (m *Manager) Push(ctx context.Context, expected ocispec.Descriptor, content io.Reader) error {
+	unlock, err := m.lock(false)
+	if err != nil {
+		return err
+	}
+	err = m.storage.Push(ctx, expected, content)
+	unlock()
+	if err != nil || m.opts.MaxSize <= 0 {
+		return err
+	}
+	exceeded, err := m.grow(expected.Size)
+	if err != nil || !exceeded {
+		return err
+	}
+	_, err = m.Prune(ctx)
+	return err
+}
+
+// grow adds n bytes to the tracked size of the cache, and reports whether the
+// cache exceeds MaxSize. The size is computed from the cached blobs if not
+// tracked yet.
+func (m *Manager) grow(n int64) (bool, error) {
+	s := m.state
+	s.sizeLock.Lock()
+	defer s.sizeLock.Unlock()
+	if s.sized {
+		s.size += n
+	} else {
+		entries, err := m.entries()
+		if err != nil {
+			return false, err
+		}
+		s.size, s.sized = totalSize(entries), true
+	}
+	return s.size > m.opts.MaxSize, nil
+}
+
+// setSize sets the tracked size of the cache.
+func (s *rootState) setSize(size int64) {
+	s.sizeLock.Lock()
+	defer s.sizeLock.Unlock()
+	s.size, s.sized = size, true
+}
+
+// resetSize drops the tracked size of the cache, to be computed again.
+func (s *rootState) resetSize() {
+	s.sizeLock.Lock()
+	defer s.sizeLock.Unlock()
+	s.size, s.sized = 0, false
+}
+
+// lock locks the cache root, exclusively or shared.
+func (m *Manager) lock(exclusive bool) (func(), error) {
+	unlock, err := lock(filepath.Join(m.root, lockFileName), exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the cache: %w", err)
+	}
+	return unlock, nil
+}
+
+// Exists returns true if the blob is cached.
+func (m *Manager) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	return m.storage.Exists(ctx, target)
+}
+
+// Prune evicts the blobs not used within MaxAge, and then the least recently
+// used blobs until the cache fits in MaxSize. Tags and referrer lists cached
+// before MaxAge are evicted as well. Pruning holds an exclusive lock
+// on the cache root so that processes sharing it do not prune concurrently.
+func (m *Manager) Prune(ctx context.Context) (PruneResult, error) {
+	unlock, err := m.lock(true)
+	if err != nil {
+		return PruneResult{}, err
+	}
+	defer unlock()
+
+	entries, err := m.entries()
+	if err != nil {
+		return PruneResult{}, err
+	}
+	// least recently used first
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].accessed.Before(entries[j].accessed)
+	})
+	total := totalSize(entries)
+	defer func() {
+		m.state.setSize(total)
+	}()
+
+	var result PruneResult
+	expiry := time.Now().Add(-m.opts.MaxAge)
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		expired := m.opts.MaxAge > 0 && e.accessed.Before(expiry)
+		oversized := m.opts.MaxSize > 0 && total > m.opts.MaxSize
+		if !expired && !oversized {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+		total -= e.size
+		result.Entries++
+		result.Bytes += e.size
+	}
+	if m.opts.MaxAge <= 0 {
+		return result, nil
+	}
+	tags, err := m.tagEntries()
+	if err != nil {
+		return result, err
+	}
+	for _, e := range tags {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if !e.accessed.Before(expiry) {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+		result.Tags++
+	}
+	return result, nil
+}
+
+// VerifyResult is the result of verifying the cache.
+type VerifyResult struct {
+	// Entries is the number of verified blobs.
+	Entries int
+	// Corrupted lists the removed blobs mismatching their digests.
+	Corrupted []digest.Digest
+	// CorruptedTags is the number of removed tags failed to be parsed.
+	CorruptedTags int
+}
+
+// Verify verifies all blobs and tags under the cache root and removes the
+// corrupted ones. Verifying holds the same lock as pruning.
+func (m *Manager) Verify(ctx context.Context) (VerifyResult, error) {
+	unlock, err := m.lock(true)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer unlock()
+	defer m.state.resetSize()
+
+	entries, err := m.entries()
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	var result VerifyResult
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		result.Entries++
+		if err := e.digest.Validate(); err == nil {
+			err = verifyFile(e.path, e.digest)
+			if err == nil || errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if !errors.Is(err, ErrCorrupted) {
+				return result, err
+			}
+		}
+		if removeErr := os.Remove(e.path); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return result, removeErr
+		}
+		result.Corrupted = append(result.Corrupted, e.digest)
+	}
+
+	tags, err := os.ReadDir(filepath.Join(m.root, tagsDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, err
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag.Name(), ".") {
+			// temporary files of tags being cached
+			continue
+		}
+		path := filepath.Join(m.root, tagsDir, tag.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return result, err
+		}
+		var entry tagEntry
+		if err := json.Unmarshal(data, &entry); err == nil && m.tagPath(entry.Reference) == path {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+		result.CorruptedTags++
+	}
+	return result, nil
+}
+
+// Stats returns the statistics of the cache. Hits and misses are counted in
+// this process only, while entries and bytes cover all processes sharing the
+// root.
+func (m *Manager) Stats() (Stats, error) {
+	entries, err := m.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+	tags, err := m.tagEntries()
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{
+		Entries: len(entries),
+		Bytes:   totalSize(entries),
+		Tags:    len(tags),
+		Hits:    m.state.hits.Load(),
+		Misses:  m.state.misses.Load(),
+	}, nil
+}
+
+// entry is a cached blob.
+type entry struct {
+	path     string
+	digest   digest.Digest
+	size     int64
+	accessed time.Time
+}
+
+// entries lists the cached blobs.
+func (m *Manager) entries() ([]entry, error) {
+	var entries []entry
+	blobs := filepath.Join(m.root, blobsDir)
+	err := filepath.WalkDir(blobs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// the blob is evicted by another process
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(blobs, path)
+		if err != nil {
+			return err
+		}
+		alg, encoded := filepath.Split(rel)
+		entries = append(entries, entry{
+			path:     path,
+			digest:   digest.NewDigestFromEncoded(digest.Algorithm(filepath.Clean(alg)), encoded),
+			size:     info.Size(),
+			accessed: info.ModTime(),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// tagEntries lists the cached tags and referrer lists, accessed when they are
+// cached.
+func (m *Manager) tagEntries() ([]entry, error) {
+	dir := filepath.Join(m.root, tagsDir)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []entry
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			// temporary files of tags being cached
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, entry{
+			path:     filepath.Join(dir, file.Name()),
+			size:     info.Size(),
+			accessed: info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// totalSize returns the total size of the entries.
+func totalSize(entries []entry) int64 {
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	return total
+}
+
+// verifyFile verifies the file against the digest.
+func verifyFile(path string, dgst digest.Digest) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return verifyBlob(file, dgst, -1)
+}
+
+// verifyBlob verifies the content against the digest, and the size unless it
+// is negative.
+func verifyBlob(r io.Reader, dgst digest.Digest, size int64) error {
+	verifier := dgst.Verifier()
+	n, err := io.Copy(verifier, r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("%s: %w: expect size %d, got %d", dgst, ErrCorrupted, size, n)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("%s: %w: digest mismatch", dgst, ErrCorrupted)
+	}
+	return nil
+}
+
+// blobPath returns the path of the blob in the cache.
+func (m *Manager) blobPath(dgst digest.Digest) string {
+	return filepath.Join(m.root, blobsDir, dgst.Algorithm().String(), dgst.Encoded())
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// pushCached pushes the blob into the cache and sets its access time.
+func pushCached(t *testing.T, m *Manager, data string, accessed time.Time) ocispec.Descriptor {
+	t.Helper()
+	desc := content.NewDescriptorFromBytes("text/plain", []byte(data))
+	require.NoError(t, m.Push(context.Background(), desc, bytes.NewReader([]byte(data))))
+	require.NoError(t, os.Chtimes(m.blobPath(desc.Digest), accessed, accessed))
+	return desc
+}
+
+func TestCacheManager(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(t.TempDir(), Options{MaxSize: 10})
+	require.NoError(t, err)
+	now := time.Now()
+	a := pushCached(t, m, "aaaaa", now.Add(-3*time.Hour))
+	b := pushCached(t, m, "bbbbb", now.Add(-2*time.Hour))
+
+	// a becomes the most recently used
+	rc, err := m.Fetch(ctx, a)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaa", string(got))
+
+	// b is evicted as the least recently used
+	c := pushCached(t, m, "ccccc", now)
+	for _, tt := range []struct {
+		desc   ocispec.Descriptor
+		exists bool
+	}{{a, true}, {b, false}, {c, true}} {
+		exists, err := m.Exists(ctx, tt.desc)
+		require.NoError(t, err)
+		assert.Equal(t, tt.exists, exists, tt.desc.Digest)
+	}
+	_, err = m.Fetch(ctx, b)
+	assert.NotNil(t, err)
+
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, Stats{Entries: 2, Bytes: 10, Hits: 1, Misses: 1}, stats)
+}
+
+func TestCacheManager_prune(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	m, err := New(root, Options{MaxAge: time.Hour})
+	require.NoError(t, err)
+	now := time.Now()
+	for _, data := range []string{"a", "b", "c"} {
+		pushCached(t, m, data, now.Add(-2*time.Hour))
+	}
+	fresh := pushCached(t, m, "d", now)
+	require.NoError(t, m.StoreTag(ctx, "localhost:5000/hello:old", fresh))
+	require.NoError(t, os.Chtimes(m.tagPath("localhost:5000/hello:old"), now.Add(-2*time.Hour), now.Add(-2*time.Hour)))
+	require.NoError(t, m.StoreTag(ctx, "localhost:5000/hello:new", fresh))
+
+	// prune concurrently as processes sharing the cache root do
+	var wg sync.WaitGroup
+	results := make([]PruneResult, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := New(root, Options{MaxAge: time.Hour})
+			if assert.NoError(t, err) {
+				results[i], err = m.Prune(ctx)
+				assert.NoError(t, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	var evicted, evictedTags int
+	for _, result := range results {
+		evicted += result.Entries
+		evictedTags += result.Tags
+	}
+	assert.Equal(t, 3, evicted)
+	assert.Equal(t, 1, evictedTags)
+
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 1, stats.Tags)
+	exists, err := m.Exists(ctx, fresh)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	_, _, err = m.LoadTag(ctx, "localhost:5000/hello:new")
+	assert.NoError(t, err)
+	_, _, err = m.LoadTag(ctx, "localhost:5000/hello:old")
+	assert.ErrorIs(t, err, errdef.ErrNotFound)
+}
+
+func TestCacheManager_pruneOnPush(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	m, err := New(root, Options{MaxSize: 10, MaxAge: time.Hour})
+	require.NoError(t, err)
+	now := time.Now()
+	old := pushCached(t, m, "aaaaa", now.Add(-2*time.Hour))
+
+	// pushing within MaxSize does not prune
+	pushCached(t, m, "bbb", now)
+	exists, err := m.Exists(ctx, old)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// the size is tracked across managers of the root
+	m, err = New(root, Options{MaxSize: 10})
+	require.NoError(t, err)
+	pushCached(t, m, "ccc", now)
+	exists, err = m.Exists(ctx, old)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stats.Bytes)
+}
+
+func TestCacheManager_concurrent(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m, err := New(root, Options{MaxSize: 16})
+			if !assert.NoError(t, err) {
+				return
+			}
+			for j := 0; j < 10; j++ {
+				data := []byte(strings.Repeat(string(rune('a'+i)), j+1))
+				desc := content.NewDescriptorFromBytes("text/plain", data)
+				if !assert.NoError(t, m.Push(ctx, desc, bytes.NewReader(data))) {
+					return
+				}
+				if rc, err := m.Fetch(ctx, desc); err == nil {
+					_, err = io.ReadAll(rc)
+					assert.NoError(t, err)
+					rc.Close()
+				} else {
+					// evicted by a concurrent push
+					assert.ErrorIs(t, err, errdef.ErrNotFound)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	m, err := New(root, Options{})
+	require.NoError(t, err)
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, stats.Bytes, int64(16))
+}
+func TestCacheManager_verify(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	m, err := New(root, Options{})
+	require.NoError(t, err)
+	now := time.Now()
+	good := pushCached(t, m, "good", now)
+	modified := pushCached(t, m, "modified", now)
+	truncated := pushCached(t, m, "truncated", now)
+	altered := pushCached(t, m, "altered", now)
+	require.NoError(t, os.WriteFile(m.blobPath(modified.Digest), []byte("MODIFIED"), 0o644))
+	require.NoError(t, os.WriteFile(m.blobPath(truncated.Digest), []byte("trunc"), 0o644))
+	require.NoError(t, os.WriteFile(m.blobPath(altered.Digest), []byte("ALTERED"), 0o644))
+	require.NoError(t, m.StoreTag(ctx, "localhost:5000/app:v1", good))
+	require.NoError(t, os.WriteFile(filepath.Join(root, tagsDir, "broken.json"), []byte("{"), 0o644))
+
+	// truncated blobs are evicted on fetching
+	_, err = m.Fetch(ctx, truncated)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	assert.ErrorIs(t, err, errdef.ErrNotFound)
+	exists, err := m.Exists(ctx, truncated)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// modified blobs are evicted once read
+	rc, err := m.Fetch(ctx, altered)
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	_, err = rc.(io.Seeker).Seek(0, io.SeekStart)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	rc.Close()
+	exists, err = m.Exists(ctx, altered)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	result, err := m.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Entries)
+	assert.Equal(t, []digest.Digest{modified.Digest}, result.Corrupted)
+	assert.Equal(t, 1, result.CorruptedTags)
+	_, _, err = m.LoadTag(ctx, "localhost:5000/app:v1")
+	assert.Nil(t, err)
+
+	result, err = m.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Entries)
+	assert.Empty(t, result.Corrupted)
+	rc, err = m.Fetch(ctx, good)
+	require.NoError(t, err)
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "good", string(got))
+
+	// verified blobs are read again from the start
+	_, err = rc.(io.Seeker).Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	got, err = io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "good", string(got))
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// Memory is an in-memory content.Storage caching blobs up to a maximum size,
+// evicting the least recently used blobs when exceeded.
+type Memory struct {
+	maxSize int64
+
+	lock   sync.Mutex
+	size   int64
+	lru    *list.List // of *memoryEntry, most recently used first
+	blobs  map[digest.Digest]*list.Element
+	tags   map[string]tagEntry
+	hits   int64
+	misses int64
+}
+
+type memoryEntry struct {
+	digest digest.Digest
+	data   []byte
+}
+
+// NewMemory returns an in-memory cache of maxSize bytes. There is no limit if
+// maxSize is less than or equal to 0.
+func NewMemory(maxSize int64) *Memory {
+	return &Memory{
+		maxSize: maxSize,
+		lru:     list.New(),
+		blobs:   make(map[digest.Digest]*list.Element),
+		tags:    make(map[string]tagEntry),
+	}
+}
+
+// Fetch fetches the cached blob and marks it as the most recently used.
+func (m *Memory) Fetch(_ context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	elem, ok := m.blobs[target.Digest]
+	if !ok {
+		m.misses++
+		return nil, fmt.Errorf("%s: %s: %w", target.Digest, target.MediaType, errdef.ErrNotFound)
+	}
+	m.hits++
+	m.lru.MoveToFront(elem)
+	return io.NopCloser(bytes.NewReader(elem.Value.(*memoryEntry).data)), nil
+}
+
+// Push verifies and caches the blob, evicting the least recently used blobs
+// if the cache exceeds the maximum size.
+func (m *Memory) Push(_ context.Context, expected ocispec.Descriptor, r io.Reader) error {
+	data, err := content.ReadAll(r, expected)
+	if err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.blobs[expected.Digest]; ok {
+		return fmt.Errorf("%s: %s: %w", expected.Digest, expected.MediaType, errdef.ErrAlreadyExists)
+	}
+	m.blobs[expected.Digest] = m.lru.PushFront(&memoryEntry{
+		digest: expected.Digest,
+		data:   data,
+	})
+	m.size += int64(len(data))
+	for m.maxSize > 0 && m.size > m.maxSize {
+		m.remove(m.lru.Back())
+	}
+	return nil
+}
+
+// Exists returns true if the blob is cached.
+func (m *Memory) Exists(_ context.Context, target ocispec.Descriptor) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, ok := m.blobs[target.Digest]
+	return ok, nil
+}
+
+// Stats returns the statistics of the cache.
+func (m *Memory) Stats() Stats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return Stats{
+		Entries: len(m.blobs),
+		Bytes:   m.size,
+		Tags:    len(m.tags),
+		Hits:    m.hits,
+		Misses:  m.misses,
+	}
+}
+
+// remove removes the element from the cache. The caller must hold the lock.
+func (m *Memory) remove(elem *list.Element) {
+	entry := m.lru.Remove(elem).(*memoryEntry)
+	delete(m.blobs, entry.digest)
+	m.size -= int64(len(entry.data))
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(10)
+	push := func(data string) ocispec.Descriptor {
+		desc := content.NewDescriptorFromBytes("text/plain", []byte(data))
+		require.NoError(t, m.Push(ctx, desc, bytes.NewReader([]byte(data))))
+		return desc
+	}
+	a := push("aaaaa")
+	b := push("bbbbb")
+	rc, err := m.Fetch(ctx, a)
+	require.NoError(t, err)
+	rc.Close()
+	c := push("ccccc")
+	for _, tt := range []struct {
+		desc   ocispec.Descriptor
+		exists bool
+	}{{a, true}, {b, false}, {c, true}} {
+		exists, err := m.Exists(ctx, tt.desc)
+		require.NoError(t, err)
+		assert.Equal(t, tt.exists, exists, tt.desc.Digest)
+	}
+	_, err = m.Fetch(ctx, b)
+	assert.NotNil(t, err)
+	assert.Equal(t, Stats{Entries: 2, Bytes: 10, Hits: 1, Misses: 1}, m.Stats())
+
+	// content mismatching the descriptor is rejected
+	d := content.NewDescriptorFromBytes("text/plain", []byte("ddddd"))
+	assert.NotNil(t, m.Push(ctx, d, bytes.NewReader([]byte("eeeee"))))
+}
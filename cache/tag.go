@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// tagsDir is the directory of the cached tags under the cache root.
+const tagsDir = "tags"
+
+// TagCache caches the descriptors resolved from references, e.g.
+// `localhost:5000/hello:v1`.
+type TagCache interface {
+	// LoadTag returns the cached descriptor of the reference and when it was
+	// cached, or errdef.ErrNotFound if not cached.
+	LoadTag(ctx context.Context, reference string) (ocispec.Descriptor, time.Time, error)
+	// StoreTag caches the descriptor of the reference.
+	StoreTag(ctx context.Context, reference string, desc ocispec.Descriptor) error
+}
+
+// tagEntry is a cached tag.
+type tagEntry struct {
+	Reference  string             `json:"reference"`
+	Descriptor ocispec.Descriptor `json:"descriptor"`
+	Updated    time.Time          `json:"updated"`
+}
+
+// LoadTag returns the cached descriptor of the reference.
+func (m *Manager) LoadTag(_ context.Context, reference string) (ocispec.Descriptor, time.Time, error) {
+	data, err := os.ReadFile(m.tagPath(reference))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ocispec.Descriptor{}, time.Time{}, fmt.Errorf("%s: %w", reference, errdef.ErrNotFound)
+		}
+		return ocispec.Descriptor{}, time.Time{}, err
+	}
+	var entry tagEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Reference != reference {
+		// treat corrupted entries as not cached to resolve the tag again
+		return ocispec.Descriptor{}, time.Time{}, fmt.Errorf("%s: %w", reference, errdef.ErrNotFound)
+	}
+	return entry.Descriptor, entry.Updated, nil
+}
+
+// StoreTag caches the descriptor of the reference. The tag is written to a
+// temporary file and renamed so that processes sharing the cache root never
+// read a partial entry.
+func (m *Manager) StoreTag(_ context.Context, reference string, desc ocispec.Descriptor) error {
+	data, err := json.Marshal(tagEntry{
+		Reference:  reference,
+		Descriptor: desc,
+		Updated:    time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(m.root, tagsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".tag-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), m.tagPath(reference))
+}
+
+// tagPath returns the path of the cached tag, named after the digest of the
+// reference as references may contain characters invalid in file names.
+func (m *Manager) tagPath(reference string) string {
+	return filepath.Join(m.root, tagsDir, digest.FromString(reference).Encoded()+".json")
+}
+
+// LoadTag returns the cached descriptor of the reference.
+func (m *Memory) LoadTag(_ context.Context, reference string) (ocispec.Descriptor, time.Time, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	entry, ok := m.tags[reference]
+	if !ok {
+		return ocispec.Descriptor{}, time.Time{}, fmt.Errorf("%s: %w", reference, errdef.ErrNotFound)
+	}
+	return entry.Descriptor, entry.Updated, nil
+}
+
+// StoreTag caches the descriptor of the reference.
+func (m *Memory) StoreTag(_ context.Context, reference string, desc ocispec.Descriptor) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tags[reference] = tagEntry{
+		Reference:  reference,
+		Descriptor: desc,
+		Updated:    time.Now(),
+	}
+	return nil
+}
@@ -0,0 +1,389 @@
+package artifacts
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/errdef"
+
+	"github.com/koolay/oras-sdk/cache"
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestRunPull_cacheStats(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	c := option.Cache{Root: t.TempDir()}
+	for i := 0; i < 2; i++ {
+		opts := newPullOptions(reg, "app", "v1", t.TempDir())
+		opts.Cache = c
+		_, err := RunPull(context.Background(), opts)
+		require.NoError(t, err)
+	}
+
+	// the statistics are shared by the managers of the root
+	m, err := c.Manager()
+	require.NoError(t, err)
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Positive(t, stats.Hits)
+	assert.Positive(t, stats.Misses)
+}
+
+func TestRunPull_cache(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	reg.PushArtifact("app", "v2", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "b.txt", MediaType: "text/plain", Content: []byte("b")},
+		},
+	})
+	root := t.TempDir()
+	t.Setenv("ORAS_CACHE", root)
+
+	opts := newPullOptions(reg, "app", "v1", t.TempDir())
+	_, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	m, err := cache.New(root, cache.Options{})
+	require.NoError(t, err)
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Entries, "manifest, config and layer")
+
+	// pushing to the cache evicts blobs beyond the maximum size
+	opts = newPullOptions(reg, "app", "v2", t.TempDir())
+	opts.Cache.MaxSize = 1
+	_, err = RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	stats, err = m.Stats()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, stats.Bytes, int64(1))
+}
+
+func TestRunPull_cacheConcurrent(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+			{Name: "b.txt", MediaType: "text/plain", Content: []byte("b")},
+		},
+	})
+	root := t.TempDir()
+
+	// pulls racing to cache the same blobs all succeed
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dir := t.TempDir()
+			opts := newPullOptions(reg, "app", "v1", dir)
+			opts.Cache.Root = root
+			_, err := RunPull(context.Background(), opts)
+			if assert.NoError(t, err) {
+				got, err := os.ReadFile(filepath.Join(dir, "b.txt"))
+				assert.NoError(t, err)
+				assert.Equal(t, "b", string(got))
+			}
+		}()
+	}
+	wg.Wait()
+
+	m, err := cache.New(root, cache.Options{})
+	require.NoError(t, err)
+	stats, err := m.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 4, stats.Entries, "manifest, config and layers")
+}
+
+// blobRequests returns the requests fetching blobs.
+func blobRequests(reg *orastest.Registry) []string {
+	var requests []string
+	for _, req := range reg.Requests() {
+		if strings.HasPrefix(req, http.MethodGet+" ") && strings.Contains(req, "/blobs/") {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+func TestRunPull_cacheOptions(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	t.Setenv("ORAS_CACHE", t.TempDir())
+	envCache, err := cache.New(os.Getenv("ORAS_CACHE"), cache.Options{})
+	require.NoError(t, err)
+	root := t.TempDir()
+	rootCache, err := cache.New(root, cache.Options{})
+	require.NoError(t, err)
+	pull := func(c option.Cache) {
+		t.Helper()
+		opts := newPullOptions(reg, "app", "v1", t.TempDir())
+		opts.Cache = c
+		reg.ResetRequests()
+		_, err := RunPull(context.Background(), opts)
+		require.NoError(t, err)
+	}
+	entries := func(m *cache.Manager) int {
+		t.Helper()
+		stats, err := m.Stats()
+		require.NoError(t, err)
+		return stats.Entries
+	}
+
+	pull(option.Cache{Root: root, ReadOnly: true})
+	assert.Equal(t, 0, entries(rootCache))
+	pull(option.Cache{Root: root, Disabled: true})
+	assert.Equal(t, 0, entries(rootCache))
+
+	// the root takes precedence over ORAS_CACHE
+	pull(option.Cache{Root: root})
+	assert.Equal(t, 3, entries(rootCache))
+	assert.Equal(t, 0, entries(envCache))
+
+	// read-only caches still serve cached content
+	pull(option.Cache{Root: root, ReadOnly: true})
+	assert.Empty(t, blobRequests(reg))
+
+	pull(option.Cache{})
+	assert.Equal(t, 3, entries(envCache))
+}
+
+func TestRunPull_cacheStorage(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	memory := cache.NewMemory(0)
+	for i := 0; i < 2; i++ {
+		opts := newPullOptions(reg, "app", "v1", t.TempDir())
+		opts.Cache.Storage = memory
+		reg.ResetRequests()
+		_, err := RunPull(context.Background(), opts)
+		require.NoError(t, err)
+	}
+	assert.Empty(t, blobRequests(reg))
+	stats := memory.Stats()
+	assert.Equal(t, 3, stats.Entries)
+	assert.NotZero(t, stats.Hits)
+}
+
+func TestRunPull_tagCache(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	v1 := reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	pull := func(c option.Cache) (*PullResult, error) {
+		opts := newPullOptions(reg, "app", "v1", t.TempDir())
+		opts.Cache = c
+		reg.ResetRequests()
+		return RunPull(context.Background(), opts)
+	}
+	root := t.TempDir()
+	_, err := pull(option.Cache{Root: root, TagTTL: time.Hour})
+	require.NoError(t, err)
+
+	// the tag is resolved from the cache within the TTL
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "b.txt", MediaType: "text/plain", Content: []byte("b")},
+		},
+	})
+	result, err := pull(option.Cache{Root: root, TagTTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, v1.Digest, result.Root.Digest)
+	assert.Empty(t, reg.Requests())
+
+	result, err = pull(option.Cache{Root: root})
+	require.NoError(t, err)
+	assert.NotEqual(t, v1.Digest, result.Root.Digest)
+	assert.NotEmpty(t, reg.Requests())
+
+	// tags resolved without a TTL are not cached
+	result, err = pull(option.Cache{Root: root, TagTTL: time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, v1.Digest, result.Root.Digest)
+}
+
+func TestRunPull_offline(t *testing.T) {
+	for name, newCache := range map[string]func(t *testing.T) option.Cache{
+		"root":   func(t *testing.T) option.Cache { return option.Cache{Root: t.TempDir()} },
+		"memory": func(*testing.T) option.Cache { return option.Cache{Storage: cache.NewMemory(0)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			reg := orastest.NewRegistry()
+			root := reg.PushArtifact("app", "v1", orastest.Artifact{
+				Files: []orastest.File{
+					{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+				},
+			})
+			c := newCache(t)
+			// tags are cached for offline use only with a TTL
+			c.TagTTL = time.Hour
+			opts := newPullOptions(reg, "app", "v1", t.TempDir())
+			opts.Cache = c
+			_, err := RunPull(context.Background(), opts)
+			require.NoError(t, err)
+			reg.Close()
+
+			c.Offline = true
+			for _, ref := range []string{"v1", root.Digest.String()} {
+				dir := t.TempDir()
+				opts = newPullOptions(reg, "app", ref, dir)
+				opts.Cache = c
+				result, err := RunPull(context.Background(), opts)
+				require.NoError(t, err, ref)
+				assert.Equal(t, root.Digest, result.Root.Digest)
+				got, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+				assert.Nil(t, err)
+				assert.Equal(t, "a", string(got))
+			}
+
+			opts = newPullOptions(reg, "app", "v2", t.TempDir())
+			opts.Cache = c
+			_, err = RunPull(context.Background(), opts)
+			assert.ErrorIs(t, err, errdef.ErrNotFound)
+
+			opts = newPullOptions(reg, "app", "", t.TempDir())
+			opts.Cache = c
+			opts.VersionConstraint = "^1"
+			_, err = RunPull(context.Background(), opts)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "cannot be resolved offline")
+		})
+	}
+}
+
+func TestRunPull_offlineReferrers(t *testing.T) {
+	reg := orastest.NewRegistry()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	sig := reg.PushArtifact("app", "", orastest.Artifact{
+		ArtifactType: "application/vnd.test.signature",
+		Subject:      &root,
+		Files: []orastest.File{
+			{Name: "sig.txt", MediaType: "text/plain", Content: []byte("sig")},
+		},
+	})
+	c := option.Cache{Root: t.TempDir(), TagTTL: time.Hour}
+	opts := newPullOptions(reg, "app", "v1", t.TempDir())
+	opts.Cache = c
+	opts.IncludeSubject = true
+	_, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	reg.Close()
+
+	c.Offline = true
+	dir := t.TempDir()
+	opts = newPullOptions(reg, "app", "v1", dir)
+	opts.Cache = c
+	opts.IncludeSubject = true
+	result, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Referrers, 1)
+	assert.Equal(t, sig.Digest, result.Referrers[0].Root.Digest)
+	got, err := os.ReadFile(filepath.Join(dir, display.ShortDigest(sig), "sig.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "sig", string(got))
+
+	opts = newPullOptions(reg, "app", "v1", t.TempDir())
+	opts.Cache = c
+	opts.IncludeSubject = true
+	opts.ReferrerArtifactType = "application/vnd.test.sbom"
+	result, err = RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Referrers)
+}
+
+// cachedBlobPath returns the path of the blob in the cache root.
+func cachedBlobPath(root string, desc ocispec.Descriptor) string {
+	return filepath.Join(root, "blobs", desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+}
+
+func TestRunPull_cacheSelfHealing(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	layer := reg.PushBlob("app", "text/plain", []byte("a"))
+	reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+		},
+	})
+	root := t.TempDir()
+	pull := func() (string, error) {
+		t.Helper()
+		dir := t.TempDir()
+		opts := newPullOptions(reg, "app", "v1", dir)
+		opts.Cache.Root = root
+		reg.ResetRequests()
+		if _, err := RunPull(context.Background(), opts); err != nil {
+			return "", err
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+		require.NoError(t, err)
+		return string(got), nil
+	}
+	got, err := pull()
+	require.NoError(t, err)
+	assert.Equal(t, "a", got)
+
+	// a truncated blob is fetched from the origin again
+	require.NoError(t, os.WriteFile(cachedBlobPath(root, layer), nil, 0o644))
+	got, err = pull()
+	require.NoError(t, err)
+	assert.Equal(t, "a", got)
+	assert.Contains(t, blobRequests(reg), http.MethodGet+" /v2/app/blobs/"+layer.Digest.String())
+
+	// a modified blob of the same size is fetched from the origin again
+	require.NoError(t, os.WriteFile(cachedBlobPath(root, layer), []byte("b"), 0o644))
+	got, err = pull()
+	require.NoError(t, err)
+	assert.Equal(t, "a", got)
+	assert.Contains(t, blobRequests(reg), http.MethodGet+" /v2/app/blobs/"+layer.Digest.String())
+	cached, err := os.ReadFile(cachedBlobPath(root, layer))
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(cached))
+
+	m, err := cache.New(root, cache.Options{})
+	require.NoError(t, err)
+	result, err := m.Verify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Entries)
+	assert.Empty(t, result.Corrupted)
+}
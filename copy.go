@@ -0,0 +1,188 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/exp/slices"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/docker"
+	"github.com/koolay/oras-sdk/graph"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type CopyOptions struct {
+	option.Common
+	option.Platform
+	option.BinaryTarget
+
+	concurrency int
+	// Recursive copies the referrers of the artifact as well.
+	Recursive bool
+	// ExtraTags are tagged to the copied manifest in addition to the tag in
+	// the destination reference.
+	ExtraTags []string
+}
+
+func RunCopy(ctx context.Context, opts CopyOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	ctx = display.WithOperation(ctx, display.OperationCopy)
+	handler, done := opts.Events()
+	defer done()
+
+	// Prepare source and destination
+	src, dst, err := opts.NewTargets(ctx, opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if opts.To.VersionConstraint != "" {
+		return ocispec.Descriptor{}, errors.New("version constraint cannot be used with the destination")
+	}
+	if err := opts.From.ResolveVersion(ctx, src); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.EnsureSourceTargetReferenceNotEmpty(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	desc, err := doCopy(ctx, src, dst, opts, handler)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if from, err := digest.Parse(opts.From.Reference); err == nil && from != desc.Digest {
+		// correct source digest
+		opts.From.RawReference = fmt.Sprintf("%s@%s", opts.From.Path, desc.Digest.String())
+	}
+	copied := opts.From.AnnotatedReference() + " => " + opts.To.AnnotatedReference()
+	if err := handler.OnStatus(ctx, "Copied", copied); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if len(opts.ExtraTags) != 0 {
+		tagNOpts := oras.DefaultTagNOptions
+		tagNOpts.Concurrency = opts.concurrency
+		tagger := display.NewTagStatusPrinter(dst, handler)
+		srcRef := opts.To.Reference
+		if srcRef == "" {
+			srcRef = desc.Digest.String()
+		}
+		if _, err = oras.TagN(ctx, tagger, srcRef, opts.ExtraTags, tagNOpts); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+
+	if err := handler.OnStatus(ctx, "Digest:", desc.Digest.String()); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+func doCopy(
+	ctx context.Context,
+	src oras.ReadOnlyGraphTarget,
+	dst oras.GraphTarget,
+	opts CopyOptions,
+	handler display.EventHandler,
+) (ocispec.Descriptor, error) {
+	// Prepare copy options
+	extendedCopyOptions := oras.DefaultExtendedCopyOptions
+	extendedCopyOptions.Concurrency = opts.concurrency
+	extendedCopyOptions.FindPredecessors = func(
+		ctx context.Context,
+		src content.ReadOnlyGraphStorage,
+		desc ocispec.Descriptor,
+	) ([]ocispec.Descriptor, error) {
+		return graph.Referrers(ctx, src, desc, "")
+	}
+	updateEventOption(&extendedCopyOptions.CopyGraphOptions, dst, handler)
+	src = display.NewTrackedGraphTarget(src, handler)
+
+	var desc ocispec.Descriptor
+	var err error
+	rOpts := oras.DefaultResolveOptions
+	rOpts.TargetPlatform = opts.Platform.Platform
+	switch {
+	case opts.Recursive:
+		desc, err = oras.Resolve(ctx, src, opts.From.Reference, rOpts)
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("failed to resolve %s: %w", opts.From.Reference, err)
+		}
+		err = recursiveCopy(ctx, src, dst, opts.To.Reference, desc, extendedCopyOptions)
+	case opts.To.Reference == "":
+		desc, err = oras.Resolve(ctx, src, opts.From.Reference, rOpts)
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("failed to resolve %s: %w", opts.From.Reference, err)
+		}
+		err = oras.CopyGraph(ctx, src, dst, desc, extendedCopyOptions.CopyGraphOptions)
+	default:
+		copyOptions := oras.CopyOptions{
+			CopyGraphOptions: extendedCopyOptions.CopyGraphOptions,
+		}
+		if opts.Platform.Platform != nil {
+			copyOptions.WithTargetPlatform(opts.Platform.Platform)
+		}
+		desc, err = oras.Copy(ctx, src, opts.From.Reference, dst, opts.To.Reference, copyOptions)
+	}
+	return desc, err
+}
+
+// recursiveCopy copies an artifact and its referrers from one target to another.
+// If the artifact is a manifest list or index, referrers of its manifests are copied as well.
+func recursiveCopy(
+	ctx context.Context,
+	src oras.ReadOnlyGraphTarget,
+	dst oras.Target,
+	dstRef string,
+	root ocispec.Descriptor,
+	opts oras.ExtendedCopyOptions,
+) error {
+	if root.MediaType == ocispec.MediaTypeImageIndex || root.MediaType == docker.MediaTypeManifestList {
+		fetched, err := content.FetchAll(ctx, src, root)
+		if err != nil {
+			return err
+		}
+		var index ocispec.Index
+		if err = json.Unmarshal(fetched, &index); err != nil {
+			return err
+		}
+
+		referrers, err := graph.FindPredecessors(ctx, src, index.Manifests, opts.FindPredecessors)
+		if err != nil {
+			return err
+		}
+		referrers = slices.DeleteFunc(referrers, func(desc ocispec.Descriptor) bool {
+			return content.Equal(desc, root)
+		})
+
+		findPredecessors := opts.FindPredecessors
+		opts.FindPredecessors = func(
+			ctx context.Context,
+			src content.ReadOnlyGraphStorage,
+			desc ocispec.Descriptor,
+		) ([]ocispec.Descriptor, error) {
+			descs, err := findPredecessors(ctx, src, desc)
+			if err != nil {
+				return nil, err
+			}
+			if content.Equal(desc, root) {
+				// make sure referrers of child manifests are copied by pointing them to root
+				descs = append(descs, referrers...)
+			}
+			return descs, nil
+		}
+	}
+
+	if dstRef == "" || dstRef == root.Digest.String() {
+		return oras.ExtendedCopyGraph(ctx, src, dst, root, opts.ExtendedCopyGraphOptions)
+	}
+	_, err := oras.ExtendedCopy(ctx, src, root.Digest.String(), dst, dstRef, opts)
+	return err
+}
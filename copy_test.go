@@ -0,0 +1,90 @@
+package artifacts
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+
+	"github.com/koolay/oras-sdk/graph"
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestRunCopy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	store, err := oci.New(srcPath)
+	require.NoError(t, err)
+	layer, err := oras.PushBytes(ctx, store, "text/plain", []byte("hello"))
+	require.NoError(t, err)
+	root, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1_RC4,
+		"application/vnd.test.artifact", oras.PackManifestOptions{Layers: []ocispec.Descriptor{layer}})
+	require.NoError(t, err)
+	require.NoError(t, store.Tag(ctx, root, "v1"))
+	_, err = oras.PackManifest(ctx, store, oras.PackManifestVersion1_1_RC4,
+		"application/vnd.test.signature", oras.PackManifestOptions{Subject: &root})
+	require.NoError(t, err)
+
+	dstPath := filepath.Join(dir, "dst")
+	opts := CopyOptions{}
+	opts.From.Type = option.TargetTypeOCILayout
+	opts.From.RawReference = srcPath + ":v1"
+	opts.To.Type = option.TargetTypeOCILayout
+	opts.To.RawReference = dstPath + ":v2"
+	opts.Recursive = true
+	opts.ExtraTags = []string{"latest"}
+	desc, err := RunCopy(ctx, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, root.Digest, desc.Digest)
+
+	dst, err := oci.New(dstPath)
+	require.NoError(t, err)
+	for _, tag := range []string{"v2", "latest"} {
+		desc, err = dst.Resolve(ctx, tag)
+		assert.Nil(t, err)
+		assert.Equal(t, root.Digest, desc.Digest)
+	}
+	referrers, err := graph.Referrers(ctx, dst, root, "")
+	assert.Nil(t, err)
+	assert.Len(t, referrers, 1)
+}
+
+func TestRunCopy_referrersAPI(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	sig := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &root})
+
+	dstPath := filepath.Join(t.TempDir(), "dst")
+	opts := CopyOptions{}
+	opts.From.Type = option.TargetTypeRemote
+	opts.From.RawReference = reg.Reference("app", "v1")
+	opts.From.Remote = option.NewRemote(true, "", "")
+	opts.To.Type = option.TargetTypeOCILayout
+	opts.To.RawReference = dstPath + ":v1"
+	opts.Recursive = true
+	reg.ResetRequests()
+	desc, err := RunCopy(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, root.Digest, desc.Digest)
+
+	// referrers are listed by the referrers API without fetching them to
+	// match their subjects
+	var fetches int
+	for _, req := range reg.Requests() {
+		if req == http.MethodGet+" /v2/app/manifests/"+sig.Digest.String() {
+			fetches++
+		}
+	}
+	assert.Equal(t, 1, fetches)
+	assert.Contains(t, reg.Requests(), http.MethodGet+" /v2/app/referrers/"+root.Digest.String())
+}
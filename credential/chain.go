@@ -0,0 +1,77 @@
+package credential
+
+import (
+	"context"
+	"errors"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// ErrReadOnly is returned when saving credentials to a read-only store.
+var ErrReadOnly = errors.New("credential store is read-only")
+
+// staticStore is a read-only store returning the same credential for all
+// server addresses.
+type staticStore struct {
+	cred auth.Credential
+}
+
+// NewStaticStore returns a read-only store returning cred for all server
+// addresses.
+func NewStaticStore(cred auth.Credential) Store {
+	return &staticStore{cred: cred}
+}
+
+// Get returns the static credential.
+func (s *staticStore) Get(context.Context, string) (auth.Credential, error) {
+	return s.cred, nil
+}
+
+// Put returns ErrReadOnly.
+func (s *staticStore) Put(context.Context, string, auth.Credential) error {
+	return ErrReadOnly
+}
+
+// Delete returns ErrReadOnly.
+func (s *staticStore) Delete(context.Context, string) error {
+	return ErrReadOnly
+}
+
+// Chain is a store trying its stores in order, e.g. static credentials,
+// environment variables, credential helpers and then config files.
+type Chain []Store
+
+// Get returns the first non-empty credential of the stores.
+func (c Chain) Get(ctx context.Context, serverAddress string) (auth.Credential, error) {
+	for _, store := range c {
+		cred, err := store.Get(ctx, serverAddress)
+		if err != nil {
+			return auth.EmptyCredential, err
+		}
+		if cred != auth.EmptyCredential {
+			return cred, nil
+		}
+	}
+	return auth.EmptyCredential, nil
+}
+
+// Put saves credentials to the first store which is not read-only.
+func (c Chain) Put(ctx context.Context, serverAddress string, cred auth.Credential) error {
+	for _, store := range c {
+		err := store.Put(ctx, serverAddress, cred)
+		if !errors.Is(err, ErrReadOnly) {
+			return err
+		}
+	}
+	return ErrReadOnly
+}
+
+// Delete removes credentials from all stores which are not read-only.
+func (c Chain) Delete(ctx context.Context, serverAddress string) error {
+	for _, store := range c {
+		if err := store.Delete(ctx, serverAddress); err != nil && !errors.Is(err, ErrReadOnly) {
+			return err
+		}
+	}
+	return nil
+}
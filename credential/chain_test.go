@@ -0,0 +1,43 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestChain(t *testing.T) {
+	orastest.InstallCredentialHelper(t, "fake")
+	ctx := context.Background()
+	static := auth.Credential{Username: "static", Password: "secret"}
+	helper := NewHelper("fake")
+	require.NoError(t, helper.Put(ctx, "registry.test", auth.Credential{Username: "helper", Password: "secret"}))
+
+	chain := Chain{NewStaticStore(auth.EmptyCredential), helper}
+	got, err := chain.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, "helper", got.Username)
+
+	chain = Chain{NewStaticStore(static), helper}
+	got, err = chain.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, static, got)
+
+	// the static store is skipped for writing
+	require.NoError(t, chain.Put(ctx, "other.test", static))
+	got, err = helper.Get(ctx, "other.test")
+	assert.Nil(t, err)
+	assert.Equal(t, static, got)
+	require.NoError(t, chain.Delete(ctx, "other.test"))
+	got, err = helper.Get(ctx, "other.test")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.EmptyCredential, got)
+
+	chain = Chain{NewStaticStore(static)}
+	assert.ErrorIs(t, chain.Put(ctx, "registry.test", static), ErrReadOnly)
+}
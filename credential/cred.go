@@ -5,15 +5,23 @@ import (
 	"oras.land/oras-go/v2/registry/remote/auth"
 )
 
+// Store is the interface that any credential store must implement.
+type Store = credentials.Store
+
 // NewStore generates a store based on the passed-in config file paths.
-func NewStore(configPaths ...string) (credentials.Store, error) {
-	opts := credentials.StoreOptions{AllowPlaintextPut: true}
+func NewStore(configPaths ...string) (Store, error) {
+	return NewStoreWithOptions(credentials.StoreOptions{AllowPlaintextPut: true}, configPaths...)
+}
+
+// NewStoreWithOptions generates a store based on the passed-in config file
+// paths with opts. Credentials are saved to the first config file.
+func NewStoreWithOptions(opts credentials.StoreOptions, configPaths ...string) (Store, error) {
 	if len(configPaths) == 0 {
 		// use default docker config file path
 		return credentials.NewStoreFromDocker(opts)
 	}
 
-	var stores []credentials.Store
+	var stores []Store
 	for _, config := range configPaths {
 		store, err := credentials.NewStore(config, opts)
 		if err != nil {
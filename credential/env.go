@@ -0,0 +1,88 @@
+package credential
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// envPrefix is the prefix of the environment variables of EnvStore.
+const envPrefix = "ORAS_REGISTRY_"
+
+// dockerServerAddress is the server address of docker.io in credential stores.
+const dockerServerAddress = "https://index.docker.io/v1/"
+
+// envStore is a read-only store reading credentials from environment
+// variables.
+type envStore struct{}
+
+// NewEnvStore returns a read-only store reading the credentials of a registry
+// from the environment variables
+//
+//	ORAS_REGISTRY_<HOST>_USERNAME and ORAS_REGISTRY_<HOST>_PASSWORD, or
+//	ORAS_REGISTRY_<HOST>_TOKEN for an identity token,
+//
+// where <HOST> is the registry host in upper case with characters other than
+// letters and digits replaced by `_`, e.g. `LOCALHOST_5000` for
+// `localhost:5000`. The variables of the host without port are used if none
+// is set for the host with port.
+func NewEnvStore() Store {
+	return envStore{}
+}
+
+// EnvVarPrefix returns the prefix of the environment variables of the server
+// address read by the store of NewEnvStore, e.g. `ORAS_REGISTRY_DOCKER_IO_`.
+func EnvVarPrefix(serverAddress string) string {
+	if serverAddress == dockerServerAddress {
+		serverAddress = "docker.io"
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, serverAddress)
+	return envPrefix + name + "_"
+}
+
+// Get retrieves credentials from the environment variables of the server
+// address.
+func (envStore) Get(_ context.Context, serverAddress string) (auth.Credential, error) {
+	if cred := envCredential(EnvVarPrefix(serverAddress)); cred != auth.EmptyCredential {
+		return cred, nil
+	}
+	if host, _, err := net.SplitHostPort(serverAddress); err == nil {
+		return envCredential(EnvVarPrefix(host)), nil
+	}
+	return auth.EmptyCredential, nil
+}
+
+// Put returns ErrReadOnly.
+func (envStore) Put(context.Context, string, auth.Credential) error {
+	return ErrReadOnly
+}
+
+// Delete returns ErrReadOnly.
+func (envStore) Delete(context.Context, string) error {
+	return ErrReadOnly
+}
+
+func envCredential(prefix string) auth.Credential {
+	if token := os.Getenv(prefix + "TOKEN"); token != "" {
+		return auth.Credential{RefreshToken: token}
+	}
+	username := os.Getenv(prefix + "USERNAME")
+	if username == "" {
+		return auth.EmptyCredential
+	}
+	return auth.Credential{
+		Username: username,
+		Password: os.Getenv(prefix + "PASSWORD"),
+	}
+}
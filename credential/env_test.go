@@ -0,0 +1,33 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+func TestEnvStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewEnvStore()
+	assert.Equal(t, "ORAS_REGISTRY_LOCALHOST_5000_", EnvVarPrefix("localhost:5000"))
+	assert.Equal(t, "ORAS_REGISTRY_DOCKER_IO_", EnvVarPrefix("https://index.docker.io/v1/"))
+
+	t.Setenv("ORAS_REGISTRY_REGISTRY_TEST_USERNAME", "user")
+	t.Setenv("ORAS_REGISTRY_REGISTRY_TEST_PASSWORD", "secret")
+	t.Setenv("ORAS_REGISTRY_REGISTRY_TEST_5000_TOKEN", "token")
+	got, err := store.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.Credential{Username: "user", Password: "secret"}, got)
+	got, err = store.Get(ctx, "registry.test:5000")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.Credential{RefreshToken: "token"}, got)
+	got, err = store.Get(ctx, "registry.test:6000")
+	assert.Nil(t, err)
+	assert.Equal(t, "user", got.Username)
+	got, err = store.Get(ctx, "other.test")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.EmptyCredential, got)
+	assert.ErrorIs(t, store.Put(ctx, "registry.test", got), ErrReadOnly)
+}
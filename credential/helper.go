@@ -0,0 +1,92 @@
+package credential
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	credentials "github.com/oras-project/oras-credentials-go"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// NewHelper returns a store backed by the `docker-credential-<suffix>`
+// executable following the docker credential helper protocol. The executable
+// is looked up in PATH.
+// Reference: https://docs.docker.com/engine/reference/commandline/login/#credential-helper-protocol
+func NewHelper(suffix string) Store {
+	return credentials.NewNativeStore(suffix)
+}
+
+// HelperStore is a store resolving the credential helper of each server
+// address in the same way as the `credHelpers` and `credsStore` properties of
+// a docker config file.
+type HelperStore struct {
+	// Helpers maps server addresses to the suffixes of their helpers.
+	Helpers map[string]string
+	// Default is the suffix of the helper of server addresses not in Helpers.
+	// No helper is used for them if empty.
+	Default string
+}
+
+// NewHelperStore returns a HelperStore with the per-registry helpers and the
+// default helper.
+func NewHelperStore(helpers map[string]string, defaultHelper string) *HelperStore {
+	return &HelperStore{
+		Helpers: helpers,
+		Default: defaultHelper,
+	}
+}
+
+// NewHelperStoreFromConfig returns a HelperStore with the `credHelpers` and
+// `credsStore` properties of the docker config file. Credentials saved in the
+// config file itself are not used.
+func NewHelperStoreFromConfig(configPath string) (*HelperStore, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var config struct {
+		CredentialHelpers map[string]string `json:"credHelpers"`
+		CredentialsStore  string            `json:"credsStore"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", configPath, err)
+	}
+	return NewHelperStore(config.CredentialHelpers, config.CredentialsStore), nil
+}
+
+// Get retrieves credentials from the helper of the server address. An empty
+// credential is returned if no helper is configured.
+func (s *HelperStore) Get(ctx context.Context, serverAddress string) (auth.Credential, error) {
+	helper := s.helper(serverAddress)
+	if helper == "" {
+		return auth.EmptyCredential, nil
+	}
+	return NewHelper(helper).Get(ctx, serverAddress)
+}
+
+// Put saves credentials with the helper of the server address.
+func (s *HelperStore) Put(ctx context.Context, serverAddress string, cred auth.Credential) error {
+	helper := s.helper(serverAddress)
+	if helper == "" {
+		return fmt.Errorf("no credential helper configured for %s: %w", serverAddress, ErrReadOnly)
+	}
+	return NewHelper(helper).Put(ctx, serverAddress, cred)
+}
+
+// Delete removes credentials with the helper of the server address.
+func (s *HelperStore) Delete(ctx context.Context, serverAddress string) error {
+	helper := s.helper(serverAddress)
+	if helper == "" {
+		return nil
+	}
+	return NewHelper(helper).Delete(ctx, serverAddress)
+}
+
+func (s *HelperStore) helper(serverAddress string) string {
+	if helper, ok := s.Helpers[serverAddress]; ok {
+		return helper
+	}
+	return s.Default
+}
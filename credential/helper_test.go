@@ -0,0 +1,52 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestHelperStore(t *testing.T) {
+	orastest.InstallCredentialHelper(t, "fake")
+	ctx := context.Background()
+	cred := auth.Credential{Username: "user", Password: "secret"}
+
+	store := NewHelperStore(map[string]string{"registry.test": "fake"}, "")
+	require.NoError(t, store.Put(ctx, "registry.test", cred))
+	got, err := store.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, cred, got)
+	got, err = store.Get(ctx, "other.test")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.EmptyCredential, got)
+	assert.ErrorIs(t, store.Put(ctx, "other.test", cred), ErrReadOnly)
+
+	// identity token
+	token := auth.Credential{RefreshToken: "token"}
+	require.NoError(t, store.Put(ctx, "registry.test", token))
+	got, err = store.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, token, got)
+
+	require.NoError(t, store.Delete(ctx, "registry.test"))
+	got, err = store.Get(ctx, "registry.test")
+	assert.Nil(t, err)
+	assert.Equal(t, auth.EmptyCredential, got)
+
+	// credsStore of a config file
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"credsStore":"fake"}`), 0o600))
+	store, err = NewHelperStoreFromConfig(configPath)
+	require.NoError(t, err)
+	require.NoError(t, store.Put(ctx, "other.test", cred))
+	got, err = store.Get(ctx, "other.test")
+	assert.Nil(t, err)
+	assert.Equal(t, cred, got)
+}
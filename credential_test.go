@@ -0,0 +1,53 @@
+package artifacts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/koolay/oras-sdk/credential"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestRunPull_credentialHelper(t *testing.T) {
+	orastest.InstallCredentialHelper(t, "fake")
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "hello.txt", MediaType: "text/plain", Content: []byte("hello")},
+		},
+	})
+	store := credential.NewHelperStore(map[string]string{reg.Host: "fake"}, "")
+	require.NoError(t, store.Put(context.Background(), reg.Host, auth.Credential{Username: "user", Password: "secret"}))
+
+	opts := newPullOptions(reg, "hello", "v1", t.TempDir())
+	opts.CredentialStore = store
+	_, err := RunPull(context.Background(), opts)
+	assert.Nil(t, err)
+
+	require.NoError(t, store.Delete(context.Background(), reg.Host))
+	_, err = RunPull(context.Background(), opts)
+	assert.NotNil(t, err)
+}
+
+func TestRunPull_envCredential(t *testing.T) {
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+	})
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	opts := newPullOptions(reg, "hello", "v1", t.TempDir())
+	_, err := RunPull(context.Background(), opts)
+	assert.NotNil(t, err)
+
+	t.Setenv(credential.EnvVarPrefix(reg.Host)+"USERNAME", "user")
+	t.Setenv(credential.EnvVarPrefix(reg.Host)+"PASSWORD", "secret")
+	_, err = RunPull(context.Background(), opts)
+	assert.Nil(t, err)
+}
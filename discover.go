@@ -0,0 +1,87 @@
+package artifacts
+
+import (
+	"context"
+
+	"github.com/opencontainers/go-digest"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/graph"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type DiscoverOptions struct {
+	option.Common
+	option.Platform
+	option.Target
+
+	// ArtifactType lists the referrers of the artifact type only.
+	ArtifactType string
+	// Depth limits the levels of referrers to list. All levels are listed if
+	// less than or equal to 0.
+	Depth int
+}
+
+// Discover lists the referrers of the manifest referenced by opts recursively
+// as a tree, which can be rendered by display.PrintReferrerTree,
+// display.PrintReferrerTable or display.PrintReferrerJSON. The referrers API
+// or the referrers tag schema is used as configured by the distribution
+// specification of the target.
+func Discover(ctx context.Context, opts DiscoverOptions) (*display.ReferrerNode, error) {
+	ctx, logger := opts.WithContext(ctx)
+	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ResolveVersion(ctx, target); err != nil {
+		return nil, err
+	}
+	if err := opts.EnsureReferenceNotEmpty(); err != nil {
+		return nil, err
+	}
+	resolveOpts := oras.DefaultResolveOptions
+	resolveOpts.TargetPlatform = opts.Platform.Platform
+	desc, err := oras.Resolve(ctx, target, opts.Reference, resolveOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &display.ReferrerNode{Descriptor: desc}
+	visited := map[digest.Digest]bool{desc.Digest: true}
+	if err := discoverReferrers(ctx, target, root, 1, visited, opts); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// discoverReferrers adds the referrers of node at depth recursively.
+func discoverReferrers(
+	ctx context.Context,
+	target content.ReadOnlyGraphStorage,
+	node *display.ReferrerNode,
+	depth int,
+	visited map[digest.Digest]bool,
+	opts DiscoverOptions,
+) error {
+	if opts.Depth > 0 && depth > opts.Depth {
+		return nil
+	}
+	referrers, err := graph.Referrers(ctx, target, node.Descriptor, opts.ArtifactType)
+	if err != nil {
+		return err
+	}
+	for _, referrer := range referrers {
+		if visited[referrer.Digest] {
+			continue
+		}
+		visited[referrer.Digest] = true
+		child := &display.ReferrerNode{Descriptor: referrer}
+		if err := discoverReferrers(ctx, target, child, depth+1, visited, opts); err != nil {
+			return err
+		}
+		node.Referrers = append(node.Referrers, child)
+	}
+	return nil
+}
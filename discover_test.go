@@ -0,0 +1,89 @@
+package artifacts
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestDiscover(t *testing.T) {
+	for name, regOpts := range map[string][]orastest.Option{
+		"referrers API":        nil,
+		"referrers tag schema": {orastest.WithoutReferrersAPI()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			reg := orastest.NewRegistry(regOpts...)
+			defer reg.Close()
+			root := reg.PushArtifact("app", "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+			sig := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &root})
+			sbom := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.sbom", Subject: &root})
+			sbomSig := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &sbom})
+
+			opts := DiscoverOptions{}
+			opts.Type = option.TargetTypeRemote
+			opts.RawReference = reg.Reference("app", "v1")
+			opts.Remote = option.NewRemote(true, "", "")
+			tree, err := Discover(ctx, opts)
+			require.NoError(t, err)
+			assert.Equal(t, root.Digest, tree.Digest)
+			require.Len(t, tree.Referrers, 2)
+			for _, referrer := range tree.Referrers {
+				switch referrer.Digest {
+				case sig.Digest:
+					assert.Empty(t, referrer.Referrers)
+				case sbom.Digest:
+					require.Len(t, referrer.Referrers, 1)
+					assert.Equal(t, sbomSig.Digest, referrer.Referrers[0].Digest)
+				default:
+					t.Errorf("unexpected referrer %s", referrer.Digest)
+				}
+			}
+
+			opts.Depth = 1
+			tree, err = Discover(ctx, opts)
+			require.NoError(t, err)
+			require.Len(t, tree.Referrers, 2)
+			for _, referrer := range tree.Referrers {
+				assert.Empty(t, referrer.Referrers)
+			}
+
+			opts.Depth = 0
+			opts.ArtifactType = "application/vnd.test.signature"
+			tree, err = Discover(ctx, opts)
+			require.NoError(t, err)
+			require.Len(t, tree.Referrers, 1)
+			assert.Equal(t, sig.Digest, tree.Referrers[0].Digest)
+		})
+	}
+}
+
+func TestDiscover_distributionSpec(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &root})
+
+	opts := DiscoverOptions{}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v1")
+	opts.Remote = option.NewRemote(true, "", "")
+	opts.SetDistributionSpec("v1.1-referrers-tag")
+	require.NoError(t, opts.DistributionSpec.Parse())
+	reg.ResetRequests()
+	tree, err := Discover(ctx, opts)
+	require.NoError(t, err)
+	// the registry indexes referrers for the referrers API only
+	assert.Empty(t, tree.Referrers)
+	for _, req := range reg.Requests() {
+		assert.NotContains(t, req, "/referrers/")
+	}
+	assert.Contains(t, reg.Requests(), "GET /v2/app/manifests/"+strings.Replace(root.Digest.String(), ":", "-", 1))
+}
@@ -0,0 +1,288 @@
+package display
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Operation is the kind of operation that transfer events belong to.
+type Operation string
+
+const (
+	OperationPull Operation = "pull"
+	OperationPush Operation = "push"
+	OperationCopy Operation = "copy"
+)
+
+// operationKey is the associated key type for operation entry in context.
+const operationKey contextKey = iota + 1
+
+// WithOperation returns a context carrying the operation.
+func WithOperation(ctx context.Context, op Operation) context.Context {
+	return context.WithValue(ctx, operationKey, op)
+}
+
+// OperationFrom returns the operation attached to context, or an empty
+// operation if not attached.
+func OperationFrom(ctx context.Context) Operation {
+	op, _ := ctx.Value(operationKey).(Operation)
+	return op
+}
+
+// EventHandler handles the transfer events of each descriptor.
+// The operation of an event can be retrieved with OperationFrom.
+type EventHandler interface {
+	// OnStart is called before the content is transferred.
+	OnStart(ctx context.Context, desc ocispec.Descriptor) error
+	// OnProgress is called with the number of bytes transferred so far.
+	OnProgress(ctx context.Context, desc ocispec.Descriptor, transferred int64)
+	// OnSkipped is called when the content already exists in the destination.
+	OnSkipped(ctx context.Context, desc ocispec.Descriptor) error
+	// OnRestored is called when named content is restored from a deduplicated
+	// blob instead of being transferred.
+	OnRestored(ctx context.Context, desc ocispec.Descriptor) error
+	// OnComplete is called after the content is transferred.
+	OnComplete(ctx context.Context, desc ocispec.Descriptor) error
+	// OnError is called when the transfer of the content fails.
+	OnError(ctx context.Context, desc ocispec.Descriptor, err error)
+	// OnStatus is called with the status of the operation, e.g. `Pushed` with
+	// the reference of the pushed artifact as the detail.
+	OnStatus(ctx context.Context, status string, detail string) error
+}
+
+// NopHandler is an EventHandler discarding all events.
+type NopHandler struct{}
+
+// OnStart implements EventHandler.
+func (NopHandler) OnStart(context.Context, ocispec.Descriptor) error { return nil }
+
+// OnProgress implements EventHandler.
+func (NopHandler) OnProgress(context.Context, ocispec.Descriptor, int64) {}
+
+// OnSkipped implements EventHandler.
+func (NopHandler) OnSkipped(context.Context, ocispec.Descriptor) error { return nil }
+
+// OnRestored implements EventHandler.
+func (NopHandler) OnRestored(context.Context, ocispec.Descriptor) error { return nil }
+
+// OnComplete implements EventHandler.
+func (NopHandler) OnComplete(context.Context, ocispec.Descriptor) error { return nil }
+
+// OnError implements EventHandler.
+func (NopHandler) OnError(context.Context, ocispec.Descriptor, error) {}
+
+// OnStatus implements EventHandler.
+func (NopHandler) OnStatus(context.Context, string, string) error { return nil }
+
+// statusText contains the status texts of an operation.
+type statusText struct {
+	start    string
+	skipped  string
+	restored string
+	complete string
+}
+
+var statusTexts = map[Operation]statusText{
+	OperationPull: {
+		start:    "Downloading",
+		skipped:  "Exists     ",
+		restored: "Restored   ",
+		complete: "Downloaded ",
+	},
+	OperationPush: {
+		start:    "Uploading",
+		skipped:  "Exists   ",
+		restored: "Skipped  ",
+		complete: "Uploaded ",
+	},
+	OperationCopy: {
+		start:    "Copying",
+		skipped:  "Exists ",
+		restored: "Skipped",
+		complete: "Copied ",
+	},
+}
+
+// TextHandler is an EventHandler printing transfer status lines.
+type TextHandler struct {
+	lock    sync.Mutex
+	out     io.Writer
+	verbose bool
+}
+
+// NewTextHandler returns a TextHandler writing to out. Unnamed content is only
+// printed if verbose is set.
+func NewTextHandler(out io.Writer, verbose bool) *TextHandler {
+	return &TextHandler{
+		out:     out,
+		verbose: verbose,
+	}
+}
+
+// OnStart implements EventHandler.
+func (h *TextHandler) OnStart(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.printStatus(desc, statusTexts[OperationFrom(ctx)].start)
+}
+
+// OnProgress implements EventHandler.
+func (h *TextHandler) OnProgress(context.Context, ocispec.Descriptor, int64) {}
+
+// OnSkipped implements EventHandler.
+func (h *TextHandler) OnSkipped(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.printStatus(desc, statusTexts[OperationFrom(ctx)].skipped)
+}
+
+// OnRestored implements EventHandler.
+func (h *TextHandler) OnRestored(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.printStatus(desc, statusTexts[OperationFrom(ctx)].restored)
+}
+
+// OnComplete implements EventHandler.
+func (h *TextHandler) OnComplete(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.printStatus(desc, statusTexts[OperationFrom(ctx)].complete)
+}
+
+// OnError implements EventHandler.
+func (h *TextHandler) OnError(context.Context, ocispec.Descriptor, error) {}
+
+// OnStatus implements EventHandler.
+func (h *TextHandler) OnStatus(_ context.Context, status string, detail string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, err := fmt.Fprintln(h.out, statusLine(status, detail))
+	return err
+}
+
+// printStatus prints transfer status.
+func (h *TextHandler) printStatus(desc ocispec.Descriptor, status string) error {
+	name, ok := desc.Annotations[ocispec.AnnotationTitle]
+	if !ok {
+		// no status for unnamed content
+		if !h.verbose {
+			return nil
+		}
+		name = desc.MediaType
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, err := fmt.Fprintln(h.out, status, ShortDigest(desc), name)
+	return err
+}
+
+// event is a transfer event emitted by JSONHandler.
+type event struct {
+	Time        time.Time `json:"time"`
+	Operation   Operation `json:"operation,omitempty"`
+	Event       string    `json:"event"`
+	MediaType   string    `json:"mediaType"`
+	Digest      string    `json:"digest"`
+	Size        int64     `json:"size"`
+	Name        string    `json:"name,omitempty"`
+	Transferred int64     `json:"transferred,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// statusEvent is a status event emitted by JSONHandler.
+type statusEvent struct {
+	Time      time.Time `json:"time"`
+	Operation Operation `json:"operation,omitempty"`
+	Event     string    `json:"event"`
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// JSONHandler is an EventHandler emitting each event as a line of JSON.
+type JSONHandler struct {
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+// NewJSONHandler returns a JSONHandler writing to out.
+func NewJSONHandler(out io.Writer) *JSONHandler {
+	return &JSONHandler{
+		enc: json.NewEncoder(out),
+	}
+}
+
+// OnStart implements EventHandler.
+func (h *JSONHandler) OnStart(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.emit(ctx, "start", desc, func(*event) {})
+}
+
+// OnProgress implements EventHandler.
+func (h *JSONHandler) OnProgress(ctx context.Context, desc ocispec.Descriptor, transferred int64) {
+	_ = h.emit(ctx, "progress", desc, func(e *event) {
+		e.Transferred = transferred
+	})
+}
+
+// OnSkipped implements EventHandler.
+func (h *JSONHandler) OnSkipped(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.emit(ctx, "skipped", desc, func(*event) {})
+}
+
+// OnRestored implements EventHandler.
+func (h *JSONHandler) OnRestored(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.emit(ctx, "restored", desc, func(*event) {})
+}
+
+// OnComplete implements EventHandler.
+func (h *JSONHandler) OnComplete(ctx context.Context, desc ocispec.Descriptor) error {
+	return h.emit(ctx, "complete", desc, func(*event) {})
+}
+
+// OnError implements EventHandler.
+func (h *JSONHandler) OnError(ctx context.Context, desc ocispec.Descriptor, err error) {
+	_ = h.emit(ctx, "error", desc, func(e *event) {
+		e.Error = err.Error()
+	})
+}
+
+// OnStatus implements EventHandler.
+func (h *JSONHandler) OnStatus(ctx context.Context, status string, detail string) error {
+	e := statusEvent{
+		Time:      time.Now(),
+		Operation: OperationFrom(ctx),
+		Event:     "status",
+		Status:    status,
+		Detail:    detail,
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.enc.Encode(e)
+}
+
+func (h *JSONHandler) emit(
+	ctx context.Context,
+	name string,
+	desc ocispec.Descriptor,
+	update func(*event),
+) error {
+	e := event{
+		Time:      time.Now(),
+		Operation: OperationFrom(ctx),
+		Event:     name,
+		MediaType: desc.MediaType,
+		Digest:    desc.Digest.String(),
+		Size:      desc.Size,
+		Name:      desc.Annotations[ocispec.AnnotationTitle],
+	}
+	update(&e)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.enc.Encode(e)
+}
+
+// statusLine returns the line of a status.
+func statusLine(status string, detail string) string {
+	if detail == "" {
+		return status
+	}
+	return status + " " + detail
+}
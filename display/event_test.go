@@ -0,0 +1,113 @@
+package display
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+)
+
+func testDescriptors() (named, unnamed ocispec.Descriptor) {
+	named = content.NewDescriptorFromBytes("text/plain", []byte("hello"))
+	named.Annotations = map[string]string{ocispec.AnnotationTitle: "hello.txt"}
+	unnamed = content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, []byte("{}"))
+	return named, unnamed
+}
+
+func TestTextHandler(t *testing.T) {
+	named, unnamed := testDescriptors()
+	ctx := WithOperation(context.Background(), OperationPull)
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, false)
+	require.NoError(t, h.OnStart(ctx, named))
+	h.OnProgress(ctx, named, 3)
+	require.NoError(t, h.OnComplete(ctx, named))
+	require.NoError(t, h.OnStart(ctx, unnamed))
+	require.NoError(t, h.OnSkipped(ctx, unnamed))
+	h.OnError(ctx, named, errors.New("failed"))
+	require.NoError(t, h.OnStatus(ctx, "Pulled", "localhost:5000/hello:v1"))
+	require.NoError(t, h.OnStatus(ctx, "Downloaded empty artifact", ""))
+	want := "Downloading " + ShortDigest(named) + " hello.txt\n" +
+		"Downloaded  " + ShortDigest(named) + " hello.txt\n" +
+		"Pulled localhost:5000/hello:v1\n" +
+		"Downloaded empty artifact\n"
+	assert.Equal(t, want, buf.String())
+
+	// unnamed content is printed in verbose mode
+	buf.Reset()
+	ctx = WithOperation(context.Background(), OperationPush)
+	h = NewTextHandler(&buf, true)
+	require.NoError(t, h.OnSkipped(ctx, unnamed))
+	require.NoError(t, h.OnRestored(ctx, named))
+	want = "Exists    " + ShortDigest(unnamed) + " " + ocispec.MediaTypeImageManifest + "\n" +
+		"Skipped   " + ShortDigest(named) + " hello.txt\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestJSONHandler(t *testing.T) {
+	named, _ := testDescriptors()
+	ctx := WithOperation(context.Background(), OperationCopy)
+	var buf bytes.Buffer
+	h := NewJSONHandler(&buf)
+	require.NoError(t, h.OnStart(ctx, named))
+	h.OnProgress(ctx, named, 3)
+	require.NoError(t, h.OnSkipped(ctx, named))
+	require.NoError(t, h.OnRestored(ctx, named))
+	require.NoError(t, h.OnComplete(ctx, named))
+	h.OnError(ctx, named, errors.New("failed"))
+	require.NoError(t, h.OnStatus(ctx, "Copied", "a => b"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 7)
+	var events []map[string]any
+	for _, line := range lines {
+		var e map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		assert.Equal(t, "copy", e["operation"])
+		assert.NotEmpty(t, e["time"])
+		events = append(events, e)
+	}
+	for i, name := range []string{"start", "progress", "skipped", "restored", "complete", "error"} {
+		assert.Equal(t, name, events[i]["event"])
+		assert.Equal(t, named.Digest.String(), events[i]["digest"])
+		assert.Equal(t, "text/plain", events[i]["mediaType"])
+		assert.Equal(t, float64(5), events[i]["size"])
+		assert.Equal(t, "hello.txt", events[i]["name"])
+	}
+	assert.Equal(t, float64(3), events[1]["transferred"])
+	assert.NotContains(t, events[0], "transferred")
+	assert.Equal(t, "failed", events[5]["error"])
+	assert.Equal(t, map[string]any{
+		"time":      events[6]["time"],
+		"operation": "copy",
+		"event":     "status",
+		"status":    "Copied",
+		"detail":    "a => b",
+	}, events[6])
+}
+
+func TestNopHandler(t *testing.T) {
+	named, _ := testDescriptors()
+	ctx := context.Background()
+	var h EventHandler = NopHandler{}
+	assert.Nil(t, h.OnStart(ctx, named))
+	h.OnProgress(ctx, named, 1)
+	assert.Nil(t, h.OnSkipped(ctx, named))
+	assert.Nil(t, h.OnRestored(ctx, named))
+	assert.Nil(t, h.OnComplete(ctx, named))
+	h.OnError(ctx, named, errors.New("failed"))
+	assert.Nil(t, h.OnStatus(ctx, "Pushed", "ref"))
+}
+
+func TestOperationFrom(t *testing.T) {
+	assert.Equal(t, Operation(""), OperationFrom(context.Background()))
+	ctx := WithOperation(context.Background(), OperationPush)
+	assert.Equal(t, OperationPush, OperationFrom(ctx))
+}
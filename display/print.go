@@ -2,96 +2,43 @@ package display
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"sync"
 
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
-	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/registry"
 )
 
-var printLock sync.Mutex
-
-// Print objects to display concurrent-safely
-func Print(a ...any) error {
-	printLock.Lock()
-	defer printLock.Unlock()
-	_, err := fmt.Println(a...)
-	return err
-}
-
-// StatusPrinter returns a tracking function for transfer status.
-func StatusPrinter(status string, verbose bool) func(context.Context, ocispec.Descriptor) error {
-	return func(ctx context.Context, desc ocispec.Descriptor) error {
-		return PrintStatus(desc, status, verbose)
-	}
-}
-
-// PrintStatus prints transfer status.
-func PrintStatus(desc ocispec.Descriptor, status string, verbose bool) error {
-	name, ok := desc.Annotations[ocispec.AnnotationTitle]
-	if !ok {
-		// no status for unnamed content
-		if !verbose {
-			return nil
-		}
-		name = desc.MediaType
-	}
-	return Print(status, ShortDigest(desc), name)
-}
-
-// PrintSuccessorStatus prints transfer status of successors.
-func PrintSuccessorStatus(
-	ctx context.Context,
-	desc ocispec.Descriptor,
-	status string,
-	fetcher content.Fetcher,
-	committed *sync.Map,
-	verbose bool,
-) error {
-	successors, err := content.Successors(ctx, fetcher, desc)
-	if err != nil {
-		return err
-	}
-	for _, s := range successors {
-		name := s.Annotations[ocispec.AnnotationTitle]
-		if v, ok := committed.Load(s.Digest.String()); ok && v != name {
-			// Reprint status for deduplicated content
-			if err := PrintStatus(s, status, verbose); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-// NewTagStatusPrinter creates a wrapper type for printing tag status.
-func NewTagStatusPrinter(target oras.Target) oras.Target {
+// NewTagStatusPrinter creates a wrapper type reporting tag status to handler.
+func NewTagStatusPrinter(target oras.Target, handler EventHandler) oras.Target {
 	if repo, ok := target.(registry.Repository); ok {
 		return &tagManifestStatusForRepo{
 			Repository: repo,
+			handler:    handler,
 		}
 	}
 	return &tagManifestStatusForTarget{
-		Target: target,
+		Target:  target,
+		handler: handler,
 	}
 }
 
-// NewTagStatusHintPrinter creates a wrapper type for printing
-// tag status and hint.
-func NewTagStatusHintPrinter(target oras.Target, refPrefix string) oras.Target {
+// NewTagStatusHintPrinter creates a wrapper type reporting
+// tag status and hint to handler.
+func NewTagStatusHintPrinter(target oras.Target, refPrefix string, handler EventHandler) oras.Target {
 	var printHint sync.Once
 	if repo, ok := target.(registry.Repository); ok {
 		return &tagManifestStatusForRepo{
 			Repository: repo,
+			handler:    handler,
 			printHint:  &printHint,
 			refPrefix:  refPrefix,
 		}
 	}
 	return &tagManifestStatusForTarget{
 		Target:    target,
+		handler:   handler,
 		printHint: &printHint,
 		refPrefix: refPrefix,
 	}
@@ -99,6 +46,7 @@ func NewTagStatusHintPrinter(target oras.Target, refPrefix string) oras.Target {
 
 type tagManifestStatusForRepo struct {
 	registry.Repository
+	handler   EventHandler
 	printHint *sync.Once
 	refPrefix string
 }
@@ -113,17 +61,18 @@ func (p *tagManifestStatusForRepo) PushReference(
 	if p.printHint != nil {
 		p.printHint.Do(func() {
 			ref := p.refPrefix + "@" + expected.Digest.String()
-			_ = Print("Tagging", ref)
+			_ = p.handler.OnStatus(ctx, "Tagging", ref)
 		})
 	}
 	if err := p.Repository.PushReference(ctx, expected, content, reference); err != nil {
 		return err
 	}
-	return Print("Tagged", reference)
+	return p.handler.OnStatus(ctx, "Tagged", reference)
 }
 
 type tagManifestStatusForTarget struct {
 	oras.Target
+	handler   EventHandler
 	printHint *sync.Once
 	refPrefix string
 }
@@ -137,12 +86,12 @@ func (p *tagManifestStatusForTarget) Tag(
 	if p.printHint != nil {
 		p.printHint.Do(func() {
 			ref := p.refPrefix + "@" + desc.Digest.String()
-			_ = Print("Tagging", ref)
+			_ = p.handler.OnStatus(ctx, "Tagging", ref)
 		})
 	}
 
 	if err := p.Target.Tag(ctx, desc, reference); err != nil {
 		return err
 	}
-	return Print("Tagged", reference)
+	return p.handler.OnStatus(ctx, "Tagged", reference)
 }
@@ -0,0 +1,327 @@
+package display
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/term"
+)
+
+const (
+	// progressInterval is the interval of redrawing progress bars.
+	progressInterval = 200 * time.Millisecond
+	// barWidth is the width of a progress bar in characters.
+	barWidth = 20
+	// defaultTTYWidth is used when the width of the terminal is unknown.
+	defaultTTYWidth = 80
+)
+
+// progress records the transfer status of a descriptor.
+type progress struct {
+	desc        ocispec.Descriptor
+	status      string
+	transferred int64
+	start       time.Time
+	done        bool
+}
+
+// ProgressHandler is an EventHandler rendering progress bars with transfer
+// speed and ETA on a TTY. Finished transfers are kept as status lines while
+// in-progress transfers and an overall summary are redrawn in place.
+type ProgressHandler struct {
+	lock sync.Mutex
+	out  io.Writer
+	// width returns the width of the terminal in columns.
+	width    func() int
+	verbose  bool
+	entries  map[string]*progress
+	active   []*progress
+	finished []*progress
+	statuses []string
+	total    int
+	start    time.Time
+	rendered int
+	stop     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// NewProgressHandler returns a ProgressHandler rendering to tty and starts
+// rendering. Stop must be called to stop rendering once the operation is done.
+// Finished unnamed content is only kept if verbose is set.
+func NewProgressHandler(tty *os.File, verbose bool) *ProgressHandler {
+	h := newProgressHandler(tty, func() int {
+		if w, _, err := term.GetSize(int(tty.Fd())); err == nil && w > 0 {
+			return w
+		}
+		return defaultTTYWidth
+	}, verbose)
+	go h.run()
+	return h
+}
+
+// newProgressHandler returns a ProgressHandler rendering to out, truncating
+// lines to the width reported by width, without starting rendering.
+func newProgressHandler(out io.Writer, width func() int, verbose bool) *ProgressHandler {
+	return &ProgressHandler{
+		out:     out,
+		width:   width,
+		verbose: verbose,
+		entries: make(map[string]*progress),
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Stop stops rendering after drawing the final status.
+func (h *ProgressHandler) Stop() {
+	h.once.Do(func() {
+		close(h.stop)
+		<-h.stopped
+	})
+}
+
+func (h *ProgressHandler) run() {
+	defer close(h.stopped)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.render(false)
+		case <-h.stop:
+			h.render(true)
+			return
+		}
+	}
+}
+
+// OnStart implements EventHandler.
+func (h *ProgressHandler) OnStart(ctx context.Context, desc ocispec.Descriptor) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.entry(desc, statusTexts[OperationFrom(ctx)].start)
+	return nil
+}
+
+// OnProgress implements EventHandler.
+func (h *ProgressHandler) OnProgress(ctx context.Context, desc ocispec.Descriptor, transferred int64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	p := h.entries[progressKey(desc)]
+	if p == nil {
+		p = h.entry(desc, statusTexts[OperationFrom(ctx)].start)
+	}
+	if !p.done {
+		p.transferred = transferred
+	}
+}
+
+// OnSkipped implements EventHandler.
+func (h *ProgressHandler) OnSkipped(ctx context.Context, desc ocispec.Descriptor) error {
+	h.finish(desc, statusTexts[OperationFrom(ctx)].skipped)
+	return nil
+}
+
+// OnRestored implements EventHandler.
+func (h *ProgressHandler) OnRestored(ctx context.Context, desc ocispec.Descriptor) error {
+	h.finish(desc, statusTexts[OperationFrom(ctx)].restored)
+	return nil
+}
+
+// OnComplete implements EventHandler.
+func (h *ProgressHandler) OnComplete(ctx context.Context, desc ocispec.Descriptor) error {
+	h.finish(desc, statusTexts[OperationFrom(ctx)].complete)
+	return nil
+}
+
+// OnError implements EventHandler.
+func (h *ProgressHandler) OnError(_ context.Context, desc ocispec.Descriptor, _ error) {
+	h.finish(desc, "Failed     ")
+}
+
+// OnStatus implements EventHandler. The status is printed above the progress
+// bars on the next rendering.
+func (h *ProgressHandler) OnStatus(_ context.Context, status string, detail string) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.statuses = append(h.statuses, statusLine(status, detail))
+	return nil
+}
+
+// entry returns the progress of desc, creating one with status if not found.
+// The caller must hold the lock.
+func (h *ProgressHandler) entry(desc ocispec.Descriptor, status string) *progress {
+	key := progressKey(desc)
+	if p, ok := h.entries[key]; ok {
+		return p
+	}
+	p := &progress{
+		desc:   desc,
+		status: status,
+		start:  time.Now(),
+	}
+	h.entries[key] = p
+	h.active = append(h.active, p)
+	h.total++
+	return p
+}
+
+// finish marks the progress of desc as done with status.
+func (h *ProgressHandler) finish(desc ocispec.Descriptor, status string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	p := h.entry(desc, status)
+	if p.done {
+		return
+	}
+	p.status = status
+	p.transferred = desc.Size
+	p.done = true
+}
+
+// render redraws the progress bars in place. Finished transfers are printed
+// permanently above the bars.
+func (h *ProgressHandler) render(final bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	width := h.width()
+	var sb strings.Builder
+	if h.rendered > 0 {
+		// move cursor to the beginning of the first rendered line
+		fmt.Fprintf(&sb, "\x1b[%dA\r", h.rendered)
+	}
+	active := h.active[:0]
+	for _, p := range h.active {
+		if !p.done {
+			active = append(active, p)
+			continue
+		}
+		h.finished = append(h.finished, p)
+		if _, named := p.desc.Annotations[ocispec.AnnotationTitle]; named || h.verbose {
+			writeLine(&sb, fmt.Sprint(p.status, " ", ShortDigest(p.desc), " ", progressName(p.desc)), width)
+		}
+	}
+	h.active = active
+	for _, status := range h.statuses {
+		writeLine(&sb, status, width)
+	}
+	h.statuses = nil
+	for _, p := range h.active {
+		writeLine(&sb, h.barLine(p), width)
+	}
+	h.rendered = len(h.active)
+	if !final {
+		writeLine(&sb, h.summaryLine(), width)
+		h.rendered++
+	}
+	// clear lines left over from the last rendering
+	sb.WriteString("\x1b[J")
+	_, _ = io.WriteString(h.out, sb.String())
+}
+
+// barLine returns the line of an in-progress transfer.
+func (h *ProgressHandler) barLine(p *progress) string {
+	elapsed := time.Since(p.start)
+	speed := rate(p.transferred, elapsed)
+	eta := "--"
+	if speed > 0 && p.desc.Size > p.transferred {
+		remaining := float64(p.desc.Size-p.transferred) / speed
+		eta = time.Duration(remaining * float64(time.Second)).Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s %s %s %s %s/%s %3d%% %s/s ETA %s",
+		p.status,
+		ShortDigest(p.desc),
+		progressName(p.desc),
+		bar(p.transferred, p.desc.Size),
+		humanBytes(p.transferred),
+		humanBytes(p.desc.Size),
+		percent(p.transferred, p.desc.Size),
+		humanBytes(int64(speed)),
+		eta,
+	)
+}
+
+// summaryLine returns the overall progress of all transfers.
+func (h *ProgressHandler) summaryLine() string {
+	var transferred, size int64
+	for _, p := range h.entries {
+		transferred += p.transferred
+		size += p.desc.Size
+	}
+	return fmt.Sprintf("Total: %d/%d done, %s/%s, %s/s",
+		len(h.finished),
+		h.total,
+		humanBytes(transferred),
+		humanBytes(size),
+		humanBytes(int64(rate(transferred, time.Since(h.start)))),
+	)
+}
+
+// writeLine writes line truncated to width after clearing the current line.
+// The line is truncated by runes so that multi-byte characters are kept whole.
+func writeLine(sb *strings.Builder, line string, width int) {
+	if utf8.RuneCountInString(line) >= width {
+		line = string([]rune(line)[:width-1])
+	}
+	sb.WriteString("\x1b[2K")
+	sb.WriteString(line)
+	sb.WriteString("\n")
+}
+
+func progressKey(desc ocispec.Descriptor) string {
+	return desc.Digest.String() + desc.Annotations[ocispec.AnnotationTitle]
+}
+
+func progressName(desc ocispec.Descriptor) string {
+	if name, ok := desc.Annotations[ocispec.AnnotationTitle]; ok {
+		return name
+	}
+	return desc.MediaType
+}
+
+func bar(transferred, size int64) string {
+	filled := barWidth * percent(transferred, size) / 100
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled) + "]"
+}
+
+func percent(transferred, size int64) int {
+	if size <= 0 {
+		return 0
+	}
+	if transferred >= size {
+		return 100
+	}
+	return int(transferred * 100 / size)
+}
+
+// rate returns the transfer speed in bytes per second.
+func rate(transferred int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(transferred) / elapsed.Seconds()
+}
+
+// humanBytes formats n bytes in a human-readable form.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
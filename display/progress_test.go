@@ -0,0 +1,107 @@
+package display
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressHandler_render(t *testing.T) {
+	named, unnamed := testDescriptors()
+	ctx := WithOperation(context.Background(), OperationPull)
+	var buf bytes.Buffer
+	h := newProgressHandler(&buf, func() int { return 120 }, false)
+
+	require.NoError(t, h.OnStart(ctx, named))
+	require.NoError(t, h.OnStart(ctx, unnamed))
+	h.OnProgress(ctx, named, 2)
+	h.render(false)
+	output := buf.String()
+	assert.Contains(t, output, "Downloading "+ShortDigest(named)+" hello.txt [========            ] 2 B/5 B  40%")
+	assert.Contains(t, output, "Downloading "+ShortDigest(unnamed)+" "+unnamed.MediaType+" [")
+	assert.Contains(t, output, "Total: 0/2 done, 2 B/7 B, ")
+	assert.True(t, strings.HasSuffix(output, "\x1b[J"))
+
+	buf.Reset()
+	require.NoError(t, h.OnComplete(ctx, named))
+	require.NoError(t, h.OnSkipped(ctx, unnamed))
+	require.NoError(t, h.OnStatus(ctx, "Pulled", "localhost:5000/hello:v1"))
+	h.render(true)
+	output = buf.String()
+	// the bars and the summary of the last rendering are redrawn in place
+	assert.True(t, strings.HasPrefix(output, "\x1b[3A\r"))
+	assert.Contains(t, output, "\x1b[2KDownloaded  "+ShortDigest(named)+" hello.txt\n")
+	assert.Contains(t, output, "\x1b[2KPulled localhost:5000/hello:v1\n")
+	// finished unnamed content is kept only if verbose
+	assert.NotContains(t, output, ShortDigest(unnamed))
+	assert.NotContains(t, output, "Total:")
+}
+
+func TestProgressHandler_renderVerbose(t *testing.T) {
+	_, unnamed := testDescriptors()
+	ctx := WithOperation(context.Background(), OperationPush)
+	var buf bytes.Buffer
+	h := newProgressHandler(&buf, func() int { return 120 }, true)
+	require.NoError(t, h.OnComplete(ctx, unnamed))
+	h.render(true)
+	assert.Contains(t, buf.String(), "Uploaded  "+ShortDigest(unnamed)+" "+unnamed.MediaType+"\n")
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  string
+	}{
+		{name: "fit", line: "hello", width: 10, want: "hello"},
+		{name: "truncated", line: "hello world", width: 6, want: "hello"},
+		{name: "multi-byte", line: "héllo wörld", width: 9, want: "héllo wö"},
+		{name: "CJK", line: "文件文件文件", width: 4, want: "文件文"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			writeLine(&sb, tt.line, tt.width)
+			got := sb.String()
+			assert.True(t, utf8.ValidString(got))
+			assert.Equal(t, "\x1b[2K"+tt.want+"\n", got)
+		})
+	}
+}
+
+func TestBar(t *testing.T) {
+	assert.Equal(t, "["+strings.Repeat(" ", barWidth)+"]", bar(0, 10))
+	assert.Equal(t, "["+strings.Repeat("=", barWidth/2)+strings.Repeat(" ", barWidth/2)+"]", bar(5, 10))
+	assert.Equal(t, "["+strings.Repeat("=", barWidth)+"]", bar(10, 10))
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, 0, percent(5, 0))
+	assert.Equal(t, 0, percent(0, 10))
+	assert.Equal(t, 33, percent(1, 3))
+	assert.Equal(t, 100, percent(10, 10))
+	assert.Equal(t, 100, percent(20, 10))
+}
+
+func TestHumanBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{n: 0, want: "0 B"},
+		{n: 1023, want: "1023 B"},
+		{n: 1024, want: "1.0 KiB"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 5 << 20, want: "5.0 MiB"},
+		{n: 3 << 30, want: "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, humanBytes(tt.n), tt.n)
+	}
+}
@@ -0,0 +1,161 @@
+package display
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+)
+
+// trackedReader reports the progress of reading the content of a descriptor.
+type trackedReader struct {
+	io.ReadCloser
+	ctx         context.Context
+	desc        ocispec.Descriptor
+	handler     EventHandler
+	transferred int64
+}
+
+// NewTrackedReadCloser wraps rc to report the transferred bytes of desc to
+// handler.
+func NewTrackedReadCloser(
+	ctx context.Context,
+	rc io.ReadCloser,
+	desc ocispec.Descriptor,
+	handler EventHandler,
+) io.ReadCloser {
+	return &trackedReader{
+		ReadCloser: rc,
+		ctx:        ctx,
+		desc:       desc,
+		handler:    handler,
+	}
+}
+
+// Read reads from the underlying reader and reports the progress.
+func (r *trackedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.handler.OnProgress(r.ctx, r.desc, r.transferred)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.handler.OnError(r.ctx, r.desc, err)
+	}
+	return n, err
+}
+
+// trackedTarget reports the progress of fetching content from a read-only
+// target.
+type trackedTarget struct {
+	oras.ReadOnlyTarget
+	handler EventHandler
+}
+
+// NewTrackedTarget wraps target to report the progress of fetched content
+// to handler.
+func NewTrackedTarget(target oras.ReadOnlyTarget, handler EventHandler) oras.ReadOnlyTarget {
+	t := &trackedTarget{
+		ReadOnlyTarget: target,
+		handler:        handler,
+	}
+	if refFetcher, ok := target.(registry.ReferenceFetcher); ok {
+		return &trackedReferenceTarget{
+			trackedTarget:    t,
+			ReferenceFetcher: refFetcher,
+		}
+	}
+	return t
+}
+
+// Fetch fetches the content identified by the descriptor.
+func (t *trackedTarget) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := t.ReadOnlyTarget.Fetch(ctx, desc)
+	if err != nil {
+		t.handler.OnError(ctx, desc, err)
+		return nil, err
+	}
+	return NewTrackedReadCloser(ctx, rc, desc, t.handler), nil
+}
+
+type trackedReferenceTarget struct {
+	*trackedTarget
+	registry.ReferenceFetcher
+}
+
+// FetchReference fetches the content identified by the reference.
+func (t *trackedReferenceTarget) FetchReference(
+	ctx context.Context,
+	reference string,
+) (ocispec.Descriptor, io.ReadCloser, error) {
+	desc, rc, err := t.ReferenceFetcher.FetchReference(ctx, reference)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	return desc, NewTrackedReadCloser(ctx, rc, desc, t.handler), nil
+}
+
+// trackedGraphTarget reports the progress of fetching content from a read-only
+// graph target.
+type trackedGraphTarget struct {
+	oras.ReadOnlyTarget
+	content.PredecessorFinder
+}
+
+// NewTrackedGraphTarget wraps target to report the progress of fetched
+// content to handler. Referrers are listed by target if it is a
+// registry.ReferrerLister, so that they are not filtered from predecessors by
+// fetching each of them, and references are fetched by target if it is a
+// registry.ReferenceFetcher.
+func NewTrackedGraphTarget(
+	target oras.ReadOnlyGraphTarget,
+	handler EventHandler,
+) oras.ReadOnlyGraphTarget {
+	tracked := NewTrackedTarget(target, handler)
+	t := &trackedGraphTarget{
+		ReadOnlyTarget:    tracked,
+		PredecessorFinder: target,
+	}
+	refFetcher, isRefFetcher := tracked.(registry.ReferenceFetcher)
+	lister, isLister := target.(registry.ReferrerLister)
+	switch {
+	case isRefFetcher && isLister:
+		return &trackedReferenceReferrerGraphTarget{
+			trackedReferrerGraphTarget: &trackedReferrerGraphTarget{
+				trackedGraphTarget: t,
+				ReferrerLister:     lister,
+			},
+			ReferenceFetcher: refFetcher,
+		}
+	case isRefFetcher:
+		return &trackedReferenceGraphTarget{
+			trackedGraphTarget: t,
+			ReferenceFetcher:   refFetcher,
+		}
+	case isLister:
+		return &trackedReferrerGraphTarget{
+			trackedGraphTarget: t,
+			ReferrerLister:     lister,
+		}
+	}
+	return t
+}
+
+type trackedReferrerGraphTarget struct {
+	*trackedGraphTarget
+	registry.ReferrerLister
+}
+
+type trackedReferenceGraphTarget struct {
+	*trackedGraphTarget
+	registry.ReferenceFetcher
+}
+
+type trackedReferenceReferrerGraphTarget struct {
+	*trackedReferrerGraphTarget
+	registry.ReferenceFetcher
+}
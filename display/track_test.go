@@ -0,0 +1,83 @@
+package display
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry"
+)
+
+// referenceStore is a memory store fetching content by reference.
+type referenceStore struct {
+	*memory.Store
+}
+
+func (s *referenceStore) FetchReference(ctx context.Context, reference string) (ocispec.Descriptor, io.ReadCloser, error) {
+	desc, err := s.Resolve(ctx, reference)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	rc, err := s.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	return desc, rc, nil
+}
+
+// referrerStore is a reference store listing no referrers.
+type referrerStore struct {
+	referenceStore
+}
+
+func (s *referrerStore) Referrers(context.Context, ocispec.Descriptor, string, func([]ocispec.Descriptor) error) error {
+	return nil
+}
+
+func TestNewTrackedGraphTarget(t *testing.T) {
+	ctx := WithOperation(context.Background(), OperationCopy)
+	store := memory.New()
+	blob := []byte("hello")
+	desc := content.NewDescriptorFromBytes("text/plain", blob)
+	require.NoError(t, store.Push(ctx, desc, bytes.NewReader(blob)))
+	require.NoError(t, store.Tag(ctx, desc, "v1"))
+
+	tests := []struct {
+		name         string
+		target       oras.ReadOnlyGraphTarget
+		isRefFetcher bool
+		isLister     bool
+	}{
+		{name: "graph target", target: store},
+		{name: "reference fetcher", target: &referenceStore{store}, isRefFetcher: true},
+		{name: "reference fetcher and referrer lister", target: &referrerStore{referenceStore{store}}, isRefFetcher: true, isLister: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tracked := NewTrackedGraphTarget(tt.target, NewJSONHandler(&buf))
+			_, isLister := tracked.(registry.ReferrerLister)
+			assert.Equal(t, tt.isLister, isLister)
+			refFetcher, isRefFetcher := tracked.(registry.ReferenceFetcher)
+			require.Equal(t, tt.isRefFetcher, isRefFetcher)
+			if !isRefFetcher {
+				return
+			}
+			got, rc, err := refFetcher.FetchReference(ctx, "v1")
+			require.NoError(t, err)
+			defer rc.Close()
+			assert.Equal(t, desc, got)
+			data, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, blob, data)
+			assert.Contains(t, buf.String(), `"transferred":5`)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ReferrerNode is a node of a referrer tree.
+type ReferrerNode struct {
+	ocispec.Descriptor
+	// Referrers are the referrers of the node.
+	Referrers []*ReferrerNode `json:"referrers,omitempty"`
+}
+
+// PrintReferrerTree prints the referrer tree with the root named name to out.
+// Referrers are grouped by artifact type:
+//
+//	localhost:5000/hello@sha256:...
+//	└── application/vnd.example.signature
+//	    └── sha256:...
+func PrintReferrerTree(out io.Writer, name string, root *ReferrerNode) error {
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString("\n")
+	writeReferrers(&sb, root, "")
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
+
+func writeReferrers(sb *strings.Builder, node *ReferrerNode, indent string) {
+	var types []string
+	groups := make(map[string][]*ReferrerNode)
+	for _, referrer := range node.Referrers {
+		if _, ok := groups[referrer.ArtifactType]; !ok {
+			types = append(types, referrer.ArtifactType)
+		}
+		groups[referrer.ArtifactType] = append(groups[referrer.ArtifactType], referrer)
+	}
+	for i, artifactType := range types {
+		typeBranch, typeIndent := treeBranch(i == len(types)-1)
+		if artifactType == "" {
+			artifactType = "<unknown>"
+		}
+		fmt.Fprintf(sb, "%s%s%s\n", indent, typeBranch, artifactType)
+		referrers := groups[types[i]]
+		for j, referrer := range referrers {
+			branch, childIndent := treeBranch(j == len(referrers)-1)
+			fmt.Fprintf(sb, "%s%s%s%s\n", indent, typeIndent, branch, referrer.Digest)
+			writeReferrers(sb, referrer, indent+typeIndent+childIndent)
+		}
+	}
+}
+
+// treeBranch returns the branch of a tree node and the indent of its
+// children.
+func treeBranch(last bool) (branch, indent string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
+
+// PrintReferrerTable prints the referrers in the tree to out as a table with
+// their depth from the root.
+func PrintReferrerTable(out io.Writer, root *ReferrerNode) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPTH\tARTIFACT TYPE\tDIGEST\tSIZE")
+	var walk func(node *ReferrerNode, depth int)
+	walk = func(node *ReferrerNode, depth int) {
+		for _, referrer := range node.Referrers {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", depth, referrer.ArtifactType, referrer.Digest, referrer.Size)
+			walk(referrer, depth+1)
+		}
+	}
+	walk(root, 1)
+	return w.Flush()
+}
+
+// PrintReferrerJSON prints the referrer tree to out in JSON.
+func PrintReferrerJSON(out io.Writer, root *ReferrerNode) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(root)
+}
@@ -0,0 +1,58 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+)
+
+// testReferrer returns a referrer node of the artifact type.
+func testReferrer(artifactType, data string, referrers ...*ReferrerNode) *ReferrerNode {
+	desc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, []byte(data))
+	desc.ArtifactType = artifactType
+	return &ReferrerNode{Descriptor: desc, Referrers: referrers}
+}
+
+func TestPrintReferrerTree(t *testing.T) {
+	sigSig := testReferrer("application/vnd.test.signature", "sig-sig")
+	sig := testReferrer("application/vnd.test.signature", "sig", sigSig)
+	sbom := testReferrer("application/vnd.test.sbom", "sbom")
+	root := testReferrer("application/vnd.test.artifact", "root", sig, sbom)
+	name := "localhost:5000/app:v1"
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintReferrerTree(&buf, name, root))
+	want := name + "\n" +
+		"├── application/vnd.test.signature\n" +
+		"│   └── " + sig.Digest.String() + "\n" +
+		"│       └── application/vnd.test.signature\n" +
+		"│           └── " + sigSig.Digest.String() + "\n" +
+		"└── application/vnd.test.sbom\n" +
+		"    └── " + sbom.Digest.String() + "\n"
+	assert.Equal(t, want, buf.String())
+
+	buf.Reset()
+	require.NoError(t, PrintReferrerTable(&buf, root))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"DEPTH", "ARTIFACT", "TYPE", "DIGEST", "SIZE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"1", sig.ArtifactType, sig.Digest.String(), "3"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"2", sigSig.ArtifactType, sigSig.Digest.String(), "7"}, strings.Fields(lines[2]))
+	assert.Equal(t, []string{"1", sbom.ArtifactType, sbom.Digest.String(), "4"}, strings.Fields(lines[3]))
+
+	buf.Reset()
+	require.NoError(t, PrintReferrerJSON(&buf, root))
+	var got ReferrerNode
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, root.Digest, got.Digest)
+	require.Len(t, got.Referrers, 2)
+	require.Len(t, got.Referrers[0].Referrers, 1)
+	assert.Equal(t, sigSig.Digest, got.Referrers[0].Referrers[0].Digest)
+	assert.Empty(t, got.Referrers[1].Referrers)
+}
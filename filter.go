@@ -0,0 +1,86 @@
+package artifacts
+
+import (
+	"fmt"
+	"path"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/exp/slices"
+)
+
+// PullFilter matches layers by title, media type and annotations.
+type PullFilter struct {
+	// Titles are glob patterns, in the syntax of path.Match, matching the
+	// `org.opencontainers.image.title` annotation.
+	Titles []string
+	// MediaTypes are the media types of layers.
+	MediaTypes []string
+	// Annotations are the annotation key/values of layers.
+	Annotations map[string]string
+}
+
+// IsEmpty returns true if no criteria is specified.
+func (f PullFilter) IsEmpty() bool {
+	return len(f.Titles) == 0 && len(f.MediaTypes) == 0 && len(f.Annotations) == 0
+}
+
+// Validate checks the glob patterns of the filter.
+func (f PullFilter) Validate() error {
+	for _, pattern := range f.Titles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid title pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchAll returns true if desc matches all the specified criteria.
+func (f PullFilter) matchAll(desc ocispec.Descriptor) bool {
+	if len(f.Titles) != 0 && !f.matchTitle(desc) {
+		return false
+	}
+	if len(f.MediaTypes) != 0 && !slices.Contains(f.MediaTypes, desc.MediaType) {
+		return false
+	}
+	for k, v := range f.Annotations {
+		if value, ok := desc.Annotations[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// matchAny returns true if desc matches any of the specified criteria.
+func (f PullFilter) matchAny(desc ocispec.Descriptor) bool {
+	if f.matchTitle(desc) || slices.Contains(f.MediaTypes, desc.MediaType) {
+		return true
+	}
+	for k, v := range f.Annotations {
+		if value, ok := desc.Annotations[k]; ok && value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (f PullFilter) matchTitle(desc ocispec.Descriptor) bool {
+	title, ok := desc.Annotations[ocispec.AnnotationTitle]
+	if !ok {
+		return false
+	}
+	for _, pattern := range f.Titles {
+		if matched, _ := path.Match(pattern, title); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// selectLayer returns true if the layer matches all the criteria of include
+// and none of the criteria of exclude.
+func selectLayer(include, exclude PullFilter, desc ocispec.Descriptor) bool {
+	if !include.IsEmpty() && !include.matchAll(desc) {
+		return false
+	}
+	return !exclude.matchAny(desc)
+}
@@ -3,19 +3,22 @@ module github.com/koolay/oras-sdk
 go 1.21.1
 
 require (
+	github.com/Masterminds/semver/v3 v3.4.0
 	github.com/opencontainers/go-digest v1.0.0
 	github.com/opencontainers/image-spec v1.1.0-rc4
 	github.com/oras-project/oras-credentials-go v0.3.1
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d
+	golang.org/x/sync v0.3.0
+	golang.org/x/sys v0.13.0
 	golang.org/x/term v0.13.0
 	oras.land/oras-go/v2 v2.3.0
+	software.sslmate.com/src/go-pkcs12 v0.4.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sync v0.3.0 // indirect
-	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/crypto v0.11.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
@@ -0,0 +1,124 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+
+	"github.com/koolay/oras-sdk/docker"
+)
+
+// Referrers returns referrer nodes of desc in target.
+func Referrers(
+	ctx context.Context,
+	target content.ReadOnlyGraphStorage,
+	desc ocispec.Descriptor,
+	artifactType string,
+) ([]ocispec.Descriptor, error) {
+	var results []ocispec.Descriptor
+	if repo, ok := target.(registry.ReferrerLister); ok {
+		// get referrers directly
+		err := repo.Referrers(ctx, desc, artifactType, func(referrers []ocispec.Descriptor) error {
+			results = append(results, referrers...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	// find matched referrers in all predecessors
+	predecessors, err := target.Predecessors(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range predecessors {
+		switch node.MediaType {
+		case ocispec.MediaTypeImageManifest:
+			fetched, err := content.FetchAll(ctx, target, node)
+			if err != nil {
+				return nil, err
+			}
+			var image ocispec.Manifest
+			if err := json.Unmarshal(fetched, &image); err != nil {
+				return nil, err
+			}
+			if image.Subject == nil || !content.Equal(*image.Subject, desc) {
+				continue
+			}
+			node.ArtifactType = image.ArtifactType
+			if node.ArtifactType == "" {
+				node.ArtifactType = image.Config.MediaType
+			}
+			node.Annotations = image.Annotations
+		case ocispec.MediaTypeImageIndex:
+			fetched, err := content.FetchAll(ctx, target, node)
+			if err != nil {
+				return nil, err
+			}
+			var index ocispec.Index
+			if err := json.Unmarshal(fetched, &index); err != nil {
+				return nil, err
+			}
+			if index.Subject == nil || !content.Equal(*index.Subject, desc) {
+				continue
+			}
+			node.ArtifactType = index.ArtifactType
+			node.Annotations = index.Annotations
+		default:
+			continue
+		}
+		if node.ArtifactType != "" && (artifactType == "" || artifactType == node.ArtifactType) {
+			// check artifactType if not empty
+			results = append(results, node)
+		}
+	}
+	return results, nil
+}
+
+// FindPredecessors returns all predecessors of descs in src.
+func FindPredecessors(
+	ctx context.Context,
+	src content.ReadOnlyGraphStorage,
+	descs []ocispec.Descriptor,
+	findPredecessors func(context.Context, content.ReadOnlyGraphStorage, ocispec.Descriptor) ([]ocispec.Descriptor, error),
+) ([]ocispec.Descriptor, error) {
+	var results []ocispec.Descriptor
+	for _, desc := range descs {
+		predecessors, err := findPredecessors(ctx, src, desc)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, predecessors...)
+	}
+	return results, nil
+}
+
+// Successors returns the successors of a node. For a manifest, the layers
+// are returned as nodes while the subject and the config are returned
+// separately.
+func Successors(
+	ctx context.Context,
+	fetcher content.Fetcher,
+	node ocispec.Descriptor,
+) ([]ocispec.Descriptor, *ocispec.Descriptor, *ocispec.Descriptor, error) {
+	switch node.MediaType {
+	case docker.MediaTypeManifest, ocispec.MediaTypeImageManifest:
+		fetched, err := content.FetchAll(ctx, fetcher, node)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		var manifest ocispec.Manifest
+		if err := json.Unmarshal(fetched, &manifest); err != nil {
+			return nil, nil, nil, err
+		}
+		return manifest.Layers, manifest.Subject, &manifest.Config, nil
+	default:
+		nodes, err := content.Successors(ctx, fetcher, node)
+		return nodes, nil, nil, err
+	}
+}
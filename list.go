@@ -0,0 +1,171 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/koolay/oras-sdk/option"
+)
+
+type ListTagsOptions struct {
+	option.Common
+	option.Target
+
+	concurrency int
+	// Last lists the tags lexically after it only.
+	Last string
+	// Prefix lists the tags with the prefix only.
+	Prefix string
+	// Pattern lists the tags matching the regular expression only.
+	Pattern string
+	// GroupByDigest resolves the tags and groups the tags pointing at the same
+	// manifest.
+	GroupByDigest bool
+}
+
+// TagGroup is a group of tags pointing at the same manifest.
+type TagGroup struct {
+	Digest digest.Digest
+	Tags   []string
+}
+
+// ListTagsResult describes the listed tags.
+type ListTagsResult struct {
+	// Tags are the matched tags in lexical order.
+	Tags []string
+	// Groups are the matched tags grouped by digest if GroupByDigest is set,
+	// in the order of their first tag.
+	Groups []TagGroup
+}
+
+// ListTags lists the tags of the repository or OCI image layout referenced by
+// opts, whose reference should not contain a tag or digest.
+func ListTags(ctx context.Context, opts ListTagsOptions) (*ListTagsResult, error) {
+	ctx, logger := opts.WithContext(ctx)
+	var pattern *regexp.Regexp
+	if opts.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(opts.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", opts.Pattern, err)
+		}
+	}
+	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Reference != "" {
+		return nil, fmt.Errorf("unexpected tag or digest %q in %q", opts.Reference, opts.RawReference)
+	}
+
+	result := &ListTagsResult{}
+	err = target.Tags(ctx, opts.Last, func(tags []string) error {
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, opts.Prefix) {
+				continue
+			}
+			if pattern != nil && !pattern.MatchString(tag) {
+				continue
+			}
+			result.Tags = append(result.Tags, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !opts.GroupByDigest {
+		return result, nil
+	}
+
+	digests := make([]digest.Digest, len(result.Tags))
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(defaultConcurrency)
+	if opts.concurrency > 0 {
+		eg.SetLimit(opts.concurrency)
+	}
+	for i, tag := range result.Tags {
+		i, tag := i, tag
+		eg.Go(func() error {
+			desc, err := target.Resolve(egCtx, tag)
+			if err != nil {
+				return fmt.Errorf("failed to resolve tag %q: %w", tag, err)
+			}
+			digests[i] = desc.Digest
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	index := make(map[digest.Digest]int)
+	for i, tag := range result.Tags {
+		dgst := digests[i]
+		j, ok := index[dgst]
+		if !ok {
+			j = len(result.Groups)
+			index[dgst] = j
+			result.Groups = append(result.Groups, TagGroup{Digest: dgst})
+		}
+		result.Groups[j].Tags = append(result.Groups[j].Tags, tag)
+	}
+	return result, nil
+}
+
+type ListRepositoriesOptions struct {
+	option.Common
+	option.Remote
+
+	// Hostname is the registry to list, e.g. `localhost:5000`.
+	Hostname string
+	// Namespace lists the repositories under the namespace only.
+	Namespace string
+	// Last lists the repositories lexically after it only.
+	Last string
+}
+
+// errNamespaceListed stops listing repositories past the namespace.
+var errNamespaceListed = errors.New("namespace listed")
+
+// ListRepositories lists the repositories of the registry in lexical order.
+func ListRepositories(ctx context.Context, opts ListRepositoriesOptions) ([]string, error) {
+	ctx, logger := opts.WithContext(ctx)
+	reg, err := opts.NewRegistry(opts.Hostname, opts.Common, logger)
+	if err != nil {
+		return nil, err
+	}
+	var prefix string
+	last := opts.Last
+	if opts.Namespace != "" {
+		prefix = strings.TrimSuffix(opts.Namespace, "/") + "/"
+		if last == "" {
+			// skip repositories before the namespace
+			last = prefix
+		}
+	}
+	var repos []string
+	err = reg.Repositories(ctx, last, func(names []string) error {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) {
+				repos = append(repos, name)
+			} else if prefix != "" && name > prefix {
+				// the rest of the repositories sort past the namespace
+				return errNamespaceListed
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errNamespaceListed) {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (opts *ListTagsOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
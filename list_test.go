@@ -0,0 +1,142 @@
+package artifacts
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestListTags(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	v1 := reg.PushArtifact("app", "v1.0", orastest.Artifact{ArtifactType: "application/vnd.test.v1"})
+	v2 := reg.PushArtifact("app", "v2.0", orastest.Artifact{ArtifactType: "application/vnd.test.v2"})
+	require.True(t, reg.Tag("app", "v1.0", "v1"))
+	require.True(t, reg.Tag("app", "v2.0", "latest"))
+	require.True(t, reg.Tag("app", "v2.0", "v2"))
+
+	opts := ListTagsOptions{}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Host + "/app"
+	opts.Remote = option.NewRemote(true, "", "")
+	result, err := ListTags(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest", "v1", "v1.0", "v2", "v2.0"}, result.Tags)
+	assert.Nil(t, result.Groups)
+
+	opts.Last = "v1"
+	opts.Prefix = "v"
+	opts.Pattern = `^v\d+$`
+	opts.GroupByDigest = true
+	result, err = ListTags(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v2"}, result.Tags)
+
+	opts.Last = ""
+	opts.Pattern = ""
+	result, err = ListTags(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []TagGroup{
+		{Digest: v1.Digest, Tags: []string{"v1", "v1.0"}},
+		{Digest: v2.Digest, Tags: []string{"v2", "v2.0"}},
+	}, result.Groups)
+
+	opts.Pattern = "("
+	_, err = ListTags(ctx, opts)
+	assert.NotNil(t, err)
+
+	opts.Pattern = ""
+	opts.RawReference = reg.Reference("app", "v1")
+	_, err = ListTags(ctx, opts)
+	assert.NotNil(t, err)
+}
+
+func TestListTags_ociLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "layout")
+	store, err := oci.New(dir)
+	require.NoError(t, err)
+	desc, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1_RC4,
+		"application/vnd.test.artifact", oras.PackManifestOptions{})
+	require.NoError(t, err)
+	for _, tag := range []string{"v1", "latest"} {
+		require.NoError(t, store.Tag(ctx, desc, tag))
+	}
+
+	opts := ListTagsOptions{GroupByDigest: true}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = dir
+	result, err := ListTags(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"latest", "v1"}, result.Tags)
+	assert.Equal(t, []TagGroup{{Digest: desc.Digest, Tags: []string{"latest", "v1"}}}, result.Groups)
+}
+
+func TestListRepositories(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	for _, repo := range []string{"app", "ns/a", "ns/b", "nsx/c"} {
+		reg.PushArtifact(repo, "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	}
+
+	opts := ListRepositoriesOptions{
+		Remote:   option.NewRemote(true, "user", "secret"),
+		Hostname: reg.Host,
+	}
+	repos, err := ListRepositories(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "ns/a", "ns/b", "nsx/c"}, repos)
+
+	opts.Namespace = "ns"
+	repos, err = ListRepositories(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns/a", "ns/b"}, repos)
+
+	opts.Namespace = ""
+	opts.Last = "ns/a"
+	repos, err = ListRepositories(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns/b", "nsx/c"}, repos)
+
+	opts.Remote = option.NewRemote(true, "user", "wrong")
+	_, err = ListRepositories(ctx, opts)
+	assert.NotNil(t, err)
+}
+
+func TestListRepositories_namespace(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry(orastest.WithPageSize(1))
+	defer reg.Close()
+	for _, repo := range []string{"app", "ns/a", "ns/b", "nsx/c", "zzz/d", "zzz/e"} {
+		reg.PushArtifact(repo, "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	}
+
+	opts := ListRepositoriesOptions{
+		Remote:    option.NewRemote(true, "", ""),
+		Hostname:  reg.Host,
+		Namespace: "ns",
+	}
+	reg.ResetRequests()
+	repos, err := ListRepositories(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns/a", "ns/b"}, repos)
+	// paging stops at the first repository past the namespace
+	var pages int
+	for _, req := range reg.Requests() {
+		if strings.Contains(req, "/v2/_catalog") {
+			pages++
+		}
+	}
+	assert.Equal(t, 3, pages)
+}
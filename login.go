@@ -0,0 +1,82 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+
+	credentials "github.com/oras-project/oras-credentials-go"
+	"oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/koolay/oras-sdk/credential"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type LoginOptions struct {
+	option.Common
+	option.Remote
+
+	// Hostname is the registry to log in, e.g. `localhost:5000`.
+	Hostname string
+	// AllowPlaintextPut allows saving credentials in plaintext in the first
+	// config file if no credential helper is configured or detected.
+	AllowPlaintextPut bool
+}
+
+// RunLogin validates the credential of opts against the registry and saves it
+// to the credential store. The password is used as an identity token if the
+// username is empty.
+func RunLogin(ctx context.Context, opts LoginOptions) error {
+	ctx, logger := opts.WithContext(ctx)
+	handler, done := opts.Events()
+	defer done()
+	cred := opts.Credential()
+	if cred == auth.EmptyCredential {
+		return errors.New("username and password, or identity token, required")
+	}
+	store, err := loginStore(opts.Remote, credentials.StoreOptions{
+		AllowPlaintextPut:        opts.AllowPlaintextPut,
+		DetectDefaultNativeStore: true,
+	})
+	if err != nil {
+		return err
+	}
+	reg, err := opts.NewRegistry(opts.Hostname, opts.Common, logger)
+	if err != nil {
+		return err
+	}
+	if err := credentials.Login(ctx, store, reg, cred); err != nil {
+		return err
+	}
+	return handler.OnStatus(ctx, "Login Succeeded", "")
+}
+
+type LogoutOptions struct {
+	option.Common
+	option.Remote
+
+	// Hostname is the registry to log out, e.g. `localhost:5000`.
+	Hostname string
+}
+
+// RunLogout removes the credential of the registry from the credential store.
+func RunLogout(ctx context.Context, opts LogoutOptions) error {
+	handler, done := opts.Events()
+	defer done()
+	store, err := loginStore(opts.Remote, credentials.StoreOptions{})
+	if err != nil {
+		return err
+	}
+	if err := credentials.Logout(ctx, store, opts.Hostname); err != nil {
+		return err
+	}
+	return handler.OnStatus(ctx, "Removed login credentials for", opts.Hostname)
+}
+
+// loginStore returns the credential store of opts, or the store of the config
+// files with storeOpts if not set.
+func loginStore(opts option.Remote, storeOpts credentials.StoreOptions) (credential.Store, error) {
+	if opts.CredentialStore != nil {
+		return opts.CredentialStore, nil
+	}
+	return credential.NewStoreWithOptions(storeOpts, opts.Configs...)
+}
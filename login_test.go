@@ -0,0 +1,109 @@
+package artifacts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/koolay/oras-sdk/credential"
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestRunLogin(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+	})
+	configPath := filepath.Join(t.TempDir(), "config.json")
+
+	login := func(username, password string, allowPlaintextPut bool) error {
+		opts := LoginOptions{
+			Remote:            option.NewRemote(true, username, password),
+			Hostname:          reg.Host,
+			AllowPlaintextPut: allowPlaintextPut,
+		}
+		opts.Configs = []string{configPath}
+		return RunLogin(ctx, opts)
+	}
+	pull := func() error {
+		opts := newPullOptions(reg, "hello", "v1", t.TempDir())
+		opts.Configs = []string{configPath}
+		_, err := RunPull(ctx, opts)
+		return err
+	}
+
+	assert.NotNil(t, login("user", "wrong", true))
+	assert.NotNil(t, login("", "", true))
+	_, err := os.Stat(configPath)
+	assert.True(t, os.IsNotExist(err))
+	assert.NotNil(t, pull())
+
+	require.NoError(t, login("user", "secret", true))
+	assert.Nil(t, pull())
+
+	logoutOpts := LogoutOptions{Hostname: reg.Host}
+	logoutOpts.Configs = []string{configPath}
+	require.NoError(t, RunLogout(ctx, logoutOpts))
+	assert.NotNil(t, pull())
+
+	// no credential helper available
+	t.Setenv("PATH", t.TempDir())
+	assert.NotNil(t, login("user", "secret", false))
+}
+
+func TestRunLogin_identityToken(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry(orastest.WithRefreshToken("token"))
+	defer reg.Close()
+	store := credential.NewHelperStore(nil, "")
+	orastest.InstallCredentialHelper(t, "fake")
+	store.Default = "fake"
+
+	opts := LoginOptions{
+		Remote:   option.NewRemote(true, "", "token"),
+		Hostname: reg.Host,
+	}
+	opts.CredentialStore = store
+	require.NoError(t, RunLogin(ctx, opts))
+	got, err := store.Get(ctx, reg.Host)
+	assert.Nil(t, err)
+	assert.Equal(t, auth.Credential{RefreshToken: "token"}, got)
+
+	opts.Password = "wrong"
+	assert.NotNil(t, RunLogin(ctx, opts))
+}
+
+func TestRunLogin_credentialHelper(t *testing.T) {
+	ctx := context.Background()
+	orastest.InstallCredentialHelper(t, "fake")
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"credHelpers":{"`+reg.Host+`":"fake"}}`), 0o600))
+
+	// credentials are saved by the helper without plaintext put
+	opts := LoginOptions{
+		Remote:   option.NewRemote(true, "user", "secret"),
+		Hostname: reg.Host,
+	}
+	opts.Configs = []string{configPath}
+	require.NoError(t, RunLogin(ctx, opts))
+	got, err := credential.NewHelper("fake").Get(ctx, reg.Host)
+	assert.Nil(t, err)
+	assert.Equal(t, "user", got.Username)
+
+	logoutOpts := LogoutOptions{Hostname: reg.Host}
+	logoutOpts.Configs = []string{configPath}
+	require.NoError(t, RunLogout(ctx, logoutOpts))
+	got, err = credential.NewHelper("fake").Get(ctx, reg.Host)
+	assert.Nil(t, err)
+	assert.Equal(t, auth.EmptyCredential, got)
+}
@@ -0,0 +1,298 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/docker"
+	"github.com/koolay/oras-sdk/graph"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type FetchManifestOptions struct {
+	option.Common
+	option.Platform
+	option.Target
+
+	// MaxBytes limits the size of the manifest. A default of 4 MiB is used if
+	// not set.
+	MaxBytes int64
+}
+
+// FetchManifestResult is a fetched manifest.
+type FetchManifestResult struct {
+	Descriptor ocispec.Descriptor
+	// Content is the raw manifest.
+	Content []byte
+}
+
+// Pretty returns the indented manifest.
+func (r *FetchManifestResult) Pretty() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, r.Content, "", "  "); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decodes the manifest into v, e.g. an ocispec.Manifest or an
+// ocispec.Index depending on the media type of Descriptor.
+func (r *FetchManifestResult) Decode(v any) error {
+	return json.Unmarshal(r.Content, v)
+}
+
+// FetchManifest fetches the manifest referenced by opts. If a platform is
+// specified, the manifest of the platform is fetched when the reference points
+// at an index.
+func FetchManifest(ctx context.Context, opts FetchManifestOptions) (*FetchManifestResult, error) {
+	ctx, logger := opts.WithContext(ctx)
+	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ResolveVersion(ctx, target); err != nil {
+		return nil, err
+	}
+	if err := opts.EnsureReferenceNotEmpty(); err != nil {
+		return nil, err
+	}
+	fetchOpts := oras.DefaultFetchBytesOptions
+	fetchOpts.TargetPlatform = opts.Platform.Platform
+	fetchOpts.MaxBytes = opts.MaxBytes
+	desc, content, err := oras.FetchBytes(ctx, target, opts.Reference, fetchOpts)
+	if err != nil {
+		return nil, err
+	}
+	if !isManifestMediaType(desc.MediaType) {
+		return nil, fmt.Errorf("%s: %q is not a manifest media type", opts.RawReference, desc.MediaType)
+	}
+	return &FetchManifestResult{
+		Descriptor: desc,
+		Content:    content,
+	}, nil
+}
+
+type PushManifestOptions struct {
+	option.Common
+	option.Target
+
+	concurrency int
+	// Content is the manifest to push. The file at FilePath is pushed if not
+	// set.
+	Content  []byte
+	FilePath string
+	// MediaType is the media type of the manifest. It is detected from the
+	// manifest if not set.
+	MediaType string
+	// ExtraTags are tagged to the pushed manifest in addition to the tag in
+	// the target reference.
+	ExtraTags []string
+}
+
+// PushManifest pushes the manifest of opts to the target and tags it.
+func PushManifest(ctx context.Context, opts PushManifestOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	ctx = display.WithOperation(ctx, display.OperationPush)
+	handler, done := opts.Events()
+	defer done()
+	manifest := opts.Content
+	if manifest == nil {
+		if opts.FilePath == "" {
+			return ocispec.Descriptor{}, errors.New("manifest content or file path required")
+		}
+		var err error
+		if manifest, err = os.ReadFile(opts.FilePath); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	mediaType := opts.MediaType
+	if mediaType == "" {
+		var err error
+		if mediaType, err = detectManifestMediaType(manifest); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	} else if !isManifestMediaType(mediaType) {
+		return ocispec.Descriptor{}, fmt.Errorf("%q is not a manifest media type", mediaType)
+	}
+	desc := content.NewDescriptorFromBytes(mediaType, manifest)
+
+	dst, err := opts.NewTarget(opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var tags []string
+	if ref := opts.Reference; ref != "" {
+		if dgst, err := digest.Parse(ref); err == nil {
+			if dgst != desc.Digest {
+				return ocispec.Descriptor{}, fmt.Errorf("digest mismatch: %s is referenced but the manifest is %s", dgst, desc.Digest)
+			}
+		} else {
+			tags = append(tags, ref)
+		}
+	}
+	tags = append(tags, opts.ExtraTags...)
+
+	exists, err := dst.Exists(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if !exists {
+		if err := dst.Push(ctx, desc, bytes.NewReader(manifest)); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	if err := handler.OnStatus(ctx, "Pushed", opts.DisplayReference(desc.Digest)); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if len(tags) != 0 {
+		tagNOpts := oras.DefaultTagNOptions
+		tagNOpts.Concurrency = opts.concurrency
+		if _, err := oras.TagN(ctx, display.NewTagStatusPrinter(dst, handler), desc.Digest.String(), tags, tagNOpts); err != nil {
+			return ocispec.Descriptor{}, err
+		}
+	}
+	return desc, handler.OnStatus(ctx, "Digest:", desc.Digest.String())
+}
+
+type DeleteManifestOptions struct {
+	option.Common
+	option.Target
+
+	// Confirm is called with the manifest and the referrers to be deleted
+	// before deleting. Nothing is deleted if it returns false.
+	Confirm func(ctx context.Context, desc ocispec.Descriptor, referrers []ocispec.Descriptor) (bool, error)
+	// IncludeReferrers deletes the referrers of the manifest recursively as
+	// well.
+	IncludeReferrers bool
+}
+
+// DeleteManifest deletes the manifest referenced by opts, and its referrers if
+// IncludeReferrers is set, from the registry. The deleted manifests are
+// returned with referrers ahead of their subjects.
+func DeleteManifest(ctx context.Context, opts DeleteManifestOptions) ([]ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	handler, done := opts.Events()
+	defer done()
+	target, err := opts.NewExistingTarget(opts.Common, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.ResolveTargetVersion(ctx, target); err != nil {
+		return nil, err
+	}
+	if err := opts.EnsureReferenceNotEmpty(); err != nil {
+		return nil, err
+	}
+	deleter, ok := target.(content.Deleter)
+	if !ok {
+		return nil, fmt.Errorf("deleting from %s is not supported", opts.Type)
+	}
+	desc, err := target.Resolve(ctx, opts.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if !isManifestMediaType(desc.MediaType) {
+		return nil, fmt.Errorf("%s: %q is not a manifest media type", opts.RawReference, desc.MediaType)
+	}
+
+	var referrers []ocispec.Descriptor
+	if opts.IncludeReferrers {
+		visited := map[digest.Digest]bool{desc.Digest: true}
+		if referrers, err = findReferrers(ctx, target, desc, visited); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(ctx, desc, referrers)
+		if err != nil || !confirmed {
+			return nil, err
+		}
+	}
+
+	deleted := append(referrers, desc)
+	for _, node := range deleted {
+		if err := deleter.Delete(ctx, node); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", node.Digest, err)
+		}
+		if err := handler.OnStatus(ctx, "Deleted", opts.DisplayReference(node.Digest)); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, nil
+}
+
+// findReferrers returns the referrers of desc recursively, with referrers
+// ahead of their subjects.
+func findReferrers(
+	ctx context.Context,
+	target content.ReadOnlyGraphStorage,
+	desc ocispec.Descriptor,
+	visited map[digest.Digest]bool,
+) ([]ocispec.Descriptor, error) {
+	referrers, err := graph.Referrers(ctx, target, desc, "")
+	if err != nil {
+		return nil, err
+	}
+	var found []ocispec.Descriptor
+	for _, referrer := range referrers {
+		if visited[referrer.Digest] {
+			continue
+		}
+		visited[referrer.Digest] = true
+		descendants, err := findReferrers(ctx, target, referrer, visited)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, descendants...)
+		found = append(found, referrer)
+	}
+	return found, nil
+}
+
+// detectManifestMediaType detects the media type of the manifest from its
+// mediaType field, or its fields if not set.
+func detectManifestMediaType(manifest []byte) (string, error) {
+	var fields struct {
+		MediaType string           `json:"mediaType"`
+		Config    *json.RawMessage `json:"config"`
+		Manifests *json.RawMessage `json:"manifests"`
+	}
+	if err := json.Unmarshal(manifest, &fields); err != nil {
+		return "", fmt.Errorf("invalid manifest: %w", err)
+	}
+	switch {
+	case fields.MediaType != "":
+		if !isManifestMediaType(fields.MediaType) {
+			return "", fmt.Errorf("%q is not a manifest media type", fields.MediaType)
+		}
+		return fields.MediaType, nil
+	case fields.Manifests != nil:
+		return ocispec.MediaTypeImageIndex, nil
+	case fields.Config != nil:
+		return ocispec.MediaTypeImageManifest, nil
+	}
+	return "", errors.New("cannot detect the media type of the manifest")
+}
+
+func isManifestMediaType(mediaType string) bool {
+	switch mediaType {
+	case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex,
+		docker.MediaTypeManifest, docker.MediaTypeManifestList:
+		return true
+	}
+	return false
+}
+
+func (opts *PushManifestOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
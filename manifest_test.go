@@ -0,0 +1,152 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestFetchManifest(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	amd64 := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.amd64"})
+	amd64.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.arm64"})
+	arm64.Platform = &ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	index := reg.PushIndex("app", "v1", amd64, arm64)
+
+	opts := FetchManifestOptions{}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v1")
+	opts.Remote = option.NewRemote(true, "", "")
+	result, err := FetchManifest(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, index.Digest, result.Descriptor.Digest)
+	want, _ := reg.Manifest("app", "v1")
+	assert.Equal(t, want, result.Content)
+	var got ocispec.Index
+	require.NoError(t, result.Decode(&got))
+	assert.Len(t, got.Manifests, 2)
+	pretty, err := result.Pretty()
+	require.NoError(t, err)
+	assert.Contains(t, string(pretty), "\n  \"manifests\": [")
+
+	opts.SetPlatform("linux/arm64")
+	require.NoError(t, opts.Platform.Parse())
+	result, err = FetchManifest(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, arm64.Digest, result.Descriptor.Digest)
+	var manifest ocispec.Manifest
+	require.NoError(t, result.Decode(&manifest))
+	assert.Equal(t, "application/vnd.test.arm64", manifest.ArtifactType)
+}
+
+func TestPushManifest(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	config := reg.PushBlob("app", ocispec.MediaTypeEmptyJSON, []byte("{}"))
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    config,
+		Layers:    []ocispec.Descriptor{},
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	require.NoError(t, os.WriteFile(path, manifest, 0o600))
+
+	opts := PushManifestOptions{FilePath: path, ExtraTags: []string{"latest", "stable"}}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v1")
+	opts.Remote = option.NewRemote(true, "", "")
+	desc, err := PushManifest(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, ocispec.MediaTypeImageManifest, desc.MediaType)
+	for _, tag := range []string{"v1", "latest", "stable"} {
+		got, ok := reg.Manifest("app", tag)
+		assert.True(t, ok, tag)
+		assert.Equal(t, manifest, got, tag)
+	}
+
+	// push by digest
+	opts = PushManifestOptions{Content: manifest}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("other", desc.Digest.String())
+	opts.Remote = option.NewRemote(true, "", "")
+	_, err = PushManifest(ctx, opts)
+	assert.Nil(t, err)
+
+	opts.Content = []byte(`{"schemaVersion":2,"manifests":[]}`)
+	_, err = PushManifest(ctx, opts)
+	assert.NotNil(t, err, "digest mismatch")
+	opts.RawReference = reg.Reference("other", "v1")
+	opts.Content = []byte(`{"schemaVersion":2}`)
+	_, err = PushManifest(ctx, opts)
+	assert.NotNil(t, err, "unknown media type")
+	opts.Content = []byte(`{"mediaType":"application/vnd.test"}`)
+	_, err = PushManifest(ctx, opts)
+	assert.NotNil(t, err, "not a manifest")
+}
+
+func TestDeleteManifest(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	sig := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &root})
+	sigSig := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.signature", Subject: &sig})
+
+	opts := DeleteManifestOptions{IncludeReferrers: true}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v1")
+	opts.Remote = option.NewRemote(true, "", "")
+	var confirmed []ocispec.Descriptor
+	opts.Confirm = func(_ context.Context, desc ocispec.Descriptor, referrers []ocispec.Descriptor) (bool, error) {
+		assert.Equal(t, root.Digest, desc.Digest)
+		confirmed = referrers
+		return false, nil
+	}
+	deleted, err := DeleteManifest(ctx, opts)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+	require.Len(t, confirmed, 2)
+	_, ok := reg.Manifest("app", root.Digest.String())
+	assert.True(t, ok)
+
+	opts.Confirm = nil
+	deleted, err = DeleteManifest(ctx, opts)
+	require.NoError(t, err)
+	require.Len(t, deleted, 3)
+	assert.Equal(t, sigSig.Digest, deleted[0].Digest)
+	assert.Equal(t, sig.Digest, deleted[1].Digest)
+	assert.Equal(t, root.Digest, deleted[2].Digest)
+	for _, desc := range deleted {
+		_, ok := reg.Manifest("app", desc.Digest.String())
+		assert.False(t, ok)
+	}
+
+	_, err = DeleteManifest(ctx, opts)
+	assert.NotNil(t, err)
+}
+
+func TestDeleteManifest_ociLayout(t *testing.T) {
+	// no layout is created for manifests failed to be deleted
+	missing := filepath.Join(t.TempDir(), "missing")
+	opts := DeleteManifestOptions{}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = missing + ":v1"
+	_, err := DeleteManifest(context.Background(), opts)
+	assert.NotNil(t, err)
+	assert.NoDirExists(t, missing)
+}
@@ -1,33 +1,112 @@
 package option
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
-	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+
+	"github.com/koolay/oras-sdk/cache"
+	"github.com/koolay/oras-sdk/graph"
 )
 
+// cacheRootEnv is the environment variable of the cache root, used if the
+// cache root is not set.
+const cacheRootEnv = "ORAS_CACHE"
+
+// referrersSuffix is appended to the key of a digest in the tag cache to name
+// the cached index listing its referrers.
+const referrersSuffix = "#referrers"
+
 type Cache struct {
+	// Root is the root directory of the cache. The ORAS_CACHE environment
+	// variable is used if not set, and caching is disabled if neither is set.
 	Root string
+	// Disabled disables caching regardless of Root, ORAS_CACHE and Storage.
+	Disabled bool
+	// ReadOnly serves content from the cache without caching the content
+	// fetched from the origin.
+	ReadOnly bool
+	// Storage is the cache backend, e.g. cache.NewMemory. Root, MaxSize and
+	// MaxAge are ignored if set.
+	Storage content.Storage
+	// MaxSize is the maximum size of the cache in bytes, beyond which the
+	// least recently used blobs are evicted. There is no limit if not set.
+	MaxSize int64
+	// MaxAge evicts the blobs not used within the duration when the cache is
+	// pruned. There is no limit if not set.
+	MaxAge time.Duration
+	// TagTTL resolves tags and lists referrers from the cache within the
+	// duration since they are resolved from the origin. Tags and referrers are
+	// neither cached nor served from the cache if not set. They are cached if
+	// the backend is a cache.TagCache, as the default backend and cache.Memory
+	// are.
+	TagTTL time.Duration
+	// Offline serves tags, referrers and content from the cache only,
+	// regardless of TagTTL, without accessing the origin. Tags and referrers
+	// are only available if cached with TagTTL set, and version constraints
+	// cannot be resolved offline.
+	Offline bool
 }
 
-// CachedTarget gets the target storage with caching if cache root is specified.
+// CachedTarget gets the target storage with caching if caching is enabled.
 func (opts *Cache) CachedTarget(src oras.ReadOnlyTarget) (oras.ReadOnlyTarget, error) {
-	opts.Root = os.Getenv("ORAS_CACHE")
-	if opts.Root != "" {
-		ociStore, err := oci.New(opts.Root)
+	if opts.Disabled {
+		if opts.Offline {
+			return nil, errors.New("offline mode requires caching")
+		}
+		return src, nil
+	}
+	storage := opts.Storage
+	if storage == nil {
+		if opts.root() == "" {
+			if opts.Offline {
+				return nil, errors.New("offline mode requires a cache root")
+			}
+			return src, nil
+		}
+		manager, err := opts.Manager()
 		if err != nil {
 			return nil, err
 		}
-		return newCache(src, ociStore), nil
+		storage = manager
+	}
+	return newCache(src, storage, *opts), nil
+}
+
+// Manager returns the manager of the cache under the cache root, which prunes
+// the cache and reports its statistics.
+func (opts *Cache) Manager() (*cache.Manager, error) {
+	root := opts.root()
+	if root == "" {
+		return nil, errors.New("cache root not set")
+	}
+	return cache.New(root, cache.Options{
+		MaxSize: opts.MaxSize,
+		MaxAge:  opts.MaxAge,
+	})
+}
+
+// root returns the cache root, falling back to ORAS_CACHE.
+func (opts *Cache) root() string {
+	if opts.Root != "" {
+		return opts.Root
 	}
-	return src, nil
+	return os.Getenv(cacheRootEnv)
 }
 
 type closer func() error
@@ -39,14 +118,30 @@ func (fn closer) Close() error {
 // Cache target struct.
 type target struct {
 	oras.ReadOnlyTarget
-	cache content.Storage
+	cache    content.Storage
+	readOnly bool
+	offline  bool
+	tags     cache.TagCache
+	tagTTL   time.Duration
+	// repository names the source in the keys of cached tags. Tags are not
+	// cached if it is empty.
+	repository string
 }
 
-// newCache generates a new target storage with caching.
-func newCache(source oras.ReadOnlyTarget, cache content.Storage) oras.ReadOnlyTarget {
+// newCache generates a new target storage with caching configured by opts.
+func newCache(source oras.ReadOnlyTarget, storage content.Storage, opts Cache) oras.ReadOnlyTarget {
 	t := &target{
 		ReadOnlyTarget: source,
-		cache:          cache,
+		cache:          storage,
+		readOnly:       opts.ReadOnly,
+		offline:        opts.Offline,
+		tagTTL:         opts.TagTTL,
+	}
+	if tags, ok := storage.(cache.TagCache); ok {
+		if repo, ok := source.(*remote.Repository); ok {
+			t.tags = tags
+			t.repository = repo.Reference.Registry + "/" + repo.Reference.Repository
+		}
 	}
 	if refFetcher, ok := source.(registry.ReferenceFetcher); ok {
 		return &referenceTarget{
@@ -59,11 +154,14 @@ func newCache(source oras.ReadOnlyTarget, cache content.Storage) oras.ReadOnlyTa
 
 // Fetch fetches the content identified by the descriptor.
 func (t *target) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
-	rc, err := t.cache.Fetch(ctx, target)
+	rc, err := t.fetchCached(ctx, target)
 	if err == nil {
 		// Fetch from cache
 		return rc, nil
 	}
+	if t.offline {
+		return nil, offlineError(target.Digest.String(), err)
+	}
 
 	if rc, err = t.ReadOnlyTarget.Fetch(ctx, target); err != nil {
 		return nil, err
@@ -73,22 +171,46 @@ func (t *target) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadC
 	return t.cacheReadCloser(ctx, rc, target), nil
 }
 
+// fetchCached fetches the content from the cache. Seekable content is read
+// through before it is returned, so that content the cache finds corrupted,
+// and evicts, is fetched from the origin instead of failing the read.
+func (t *target) fetchCached(ctx context.Context, target ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := t.cache.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := rc.(io.Seeker); ok {
+		_, err = io.Copy(io.Discard, rc)
+		if err == nil {
+			_, err = seeker.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
 func (t *target) cacheReadCloser(
 	ctx context.Context,
 	rc io.ReadCloser,
 	target ocispec.Descriptor,
 ) io.ReadCloser {
+	if t.readOnly {
+		return rc
+	}
 	pr, pw := io.Pipe()
 	var wg sync.WaitGroup
 
 	wg.Add(1)
-	var pushErr error
 	go func() {
 		defer wg.Done()
-		pushErr = t.cache.Push(ctx, target, pr)
-		if pushErr != nil {
-			pr.CloseWithError(pushErr)
-		}
+		// failing to cache the content, e.g. as it is cached concurrently,
+		// must not fail reading it, so whatever the cache leaves unread is
+		// drained
+		_ = t.cache.Push(ctx, target, pr)
+		_, _ = io.Copy(io.Discard, pr)
 	}()
 
 	return struct {
@@ -98,13 +220,8 @@ func (t *target) cacheReadCloser(
 		Reader: io.TeeReader(rc, pw),
 		Closer: closer(func() error {
 			rcErr := rc.Close()
-			if err := pw.Close(); err != nil {
-				return err
-			}
+			_ = pw.Close()
 			wg.Wait()
-			if pushErr != nil {
-				return pushErr
-			}
 			return rcErr
 		}),
 	}
@@ -113,50 +230,222 @@ func (t *target) cacheReadCloser(
 // Exists returns true if the described content exists.
 func (t *target) Exists(ctx context.Context, desc ocispec.Descriptor) (bool, error) {
 	exists, err := t.cache.Exists(ctx, desc)
-	if err == nil && exists {
-		return true, nil
+	if t.offline || (err == nil && exists) {
+		return exists, err
 	}
 	return t.ReadOnlyTarget.Exists(ctx, desc)
 }
 
+// Resolve resolves the reference from the tag cache if cached within the TTL,
+// or from the origin otherwise.
+func (t *target) Resolve(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	if desc, err := t.resolveCached(ctx, reference); err == nil {
+		return desc, nil
+	} else if t.offline {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := t.ReadOnlyTarget.Resolve(ctx, reference)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	t.storeTag(ctx, reference, desc)
+	return desc, nil
+}
+
+// resolveCached resolves the reference from the tag cache. Tags cached beyond
+// the TTL are ignored unless offline, while digests never expire.
+func (t *target) resolveCached(ctx context.Context, reference string) (ocispec.Descriptor, error) {
+	_, err := digest.Parse(reference)
+	return t.loadTag(ctx, t.tagKey(reference), reference, err != nil)
+}
+
+// loadTag loads the entry of the key from the tag cache. Expiring entries
+// cached beyond the TTL are ignored unless offline.
+func (t *target) loadTag(ctx context.Context, key, reference string, expiring bool) (ocispec.Descriptor, error) {
+	if t.tags == nil {
+		return ocispec.Descriptor{}, offlineError(reference, errdef.ErrNotFound)
+	}
+	desc, updated, err := t.tags.LoadTag(ctx, key)
+	if err != nil {
+		return ocispec.Descriptor{}, offlineError(reference, err)
+	}
+	if expiring && !t.offline && time.Since(updated) >= t.tagTTL {
+		return ocispec.Descriptor{}, fmt.Errorf("%s: cached tag expired: %w", reference, errdef.ErrNotFound)
+	}
+	return desc, nil
+}
+
+// storeTag caches the descriptor resolved from the reference if TagTTL is set,
+// and from its digest so that it can be fetched by digest offline.
+func (t *target) storeTag(ctx context.Context, reference string, desc ocispec.Descriptor) {
+	if t.tags == nil || t.readOnly {
+		return
+	}
+	// failing to cache a tag only costs resolving it from the origin again
+	if reference != desc.Digest.String() && t.tagTTL > 0 {
+		_ = t.tags.StoreTag(ctx, t.tagKey(reference), desc)
+	}
+	_ = t.tags.StoreTag(ctx, t.tagKey(desc.Digest.String()), desc)
+}
+
+// tagKey returns the key of the reference in the tag cache.
+func (t *target) tagKey(reference string) string {
+	if _, err := digest.Parse(reference); err == nil {
+		return t.repository + "@" + reference
+	}
+	return t.repository + ":" + reference
+}
+
+// Predecessors returns the nodes directly pointing to the node in the origin.
+func (t *target) Predecessors(ctx context.Context, node ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	if t.offline {
+		return nil, offlineError(node.Digest.String(), errdef.ErrUnsupported)
+	}
+	finder, ok := t.ReadOnlyTarget.(content.ReadOnlyGraphStorage)
+	if !ok {
+		return nil, fmt.Errorf("%s: finding predecessors: %w", node.Digest, errdef.ErrUnsupported)
+	}
+	return finder.Predecessors(ctx, node)
+}
+
+// Referrers lists the referrers of desc with the artifact type, or all of its
+// referrers if artifactType is empty. The list is served from the cache within
+// the TTL or offline, and listed from the origin and cached otherwise.
+func (t *target) Referrers(
+	ctx context.Context,
+	desc ocispec.Descriptor,
+	artifactType string,
+	fn func(referrers []ocispec.Descriptor) error,
+) error {
+	referrers, err := t.referrers(ctx, desc)
+	if err != nil {
+		return err
+	}
+	if artifactType != "" {
+		var filtered []ocispec.Descriptor
+		for _, r := range referrers {
+			if r.ArtifactType == artifactType {
+				filtered = append(filtered, r)
+			}
+		}
+		referrers = filtered
+	}
+	if len(referrers) == 0 {
+		return nil
+	}
+	return fn(referrers)
+}
+
+// referrers returns all referrers of desc.
+func (t *target) referrers(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	key := t.tagKey(desc.Digest.String()) + referrersSuffix
+	index, err := t.loadTag(ctx, key, desc.Digest.String()+" referrers", true)
+	if err == nil {
+		var referrers []ocispec.Descriptor
+		if referrers, err = t.fetchReferrers(ctx, index); err == nil {
+			return referrers, nil
+		}
+		err = offlineError(desc.Digest.String()+" referrers", err)
+	}
+	if t.offline {
+		return nil, err
+	}
+
+	finder, ok := t.ReadOnlyTarget.(content.ReadOnlyGraphStorage)
+	if !ok {
+		return nil, fmt.Errorf("%s: listing referrers: %w", desc.Digest, errdef.ErrUnsupported)
+	}
+	referrers, err := graph.Referrers(ctx, finder, desc, "")
+	if err != nil {
+		return nil, err
+	}
+	t.storeReferrers(ctx, key, referrers)
+	return referrers, nil
+}
+
+// fetchReferrers fetches the cached index listing referrers.
+func (t *target) fetchReferrers(ctx context.Context, index ocispec.Descriptor) ([]ocispec.Descriptor, error) {
+	data, err := content.FetchAll(ctx, t.cache, index)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Index
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Manifests, nil
+}
+
+// storeReferrers caches the referrers as an index in the cache, tagged by the
+// key in the tag cache, if TagTTL is set.
+func (t *target) storeReferrers(ctx context.Context, key string, referrers []ocispec.Descriptor) {
+	if t.tags == nil || t.readOnly || t.tagTTL <= 0 {
+		return
+	}
+	data, err := json.Marshal(ocispec.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: referrers,
+	})
+	if err != nil {
+		return
+	}
+	index := content.NewDescriptorFromBytes(ocispec.MediaTypeImageIndex, data)
+	// failing to cache referrers only costs listing them from the origin again
+	if err := t.cache.Push(ctx, index, bytes.NewReader(data)); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+		return
+	}
+	_ = t.tags.StoreTag(ctx, key, index)
+}
+
+// offlineError returns the error of a reference not cached.
+func offlineError(reference string, err error) error {
+	return fmt.Errorf("%s: not cached for offline use: %w", reference, err)
+}
+
 // Cache referenceTarget struct.
 type referenceTarget struct {
 	*target
 	registry.ReferenceFetcher
 }
 
-// FetchReference fetches the content identified by the reference from the
-// remote and cache the fetched content.
-// Cached content will only be read via Fetch, FetchReference will always fetch
-// From origin.
+// FetchReference fetches the content identified by the reference and caches
+// the fetched content. The reference is resolved from the tag cache if cached
+// within the TTL, and from the origin otherwise.
 func (t *referenceTarget) FetchReference(
 	ctx context.Context,
 	reference string,
 ) (ocispec.Descriptor, io.ReadCloser, error) {
-	target, rc, err := t.ReferenceFetcher.FetchReference(ctx, reference)
-	if err != nil {
+	if desc, err := t.resolveCached(ctx, reference); err == nil {
+		rc, err := t.target.Fetch(ctx, desc)
+		if err != nil {
+			return ocispec.Descriptor{}, nil, err
+		}
+		return desc, rc, nil
+	} else if t.offline {
 		return ocispec.Descriptor{}, nil, err
 	}
 
-	// skip caching if the content already exists in cache
-	exists, err := t.cache.Exists(ctx, target)
+	target, rc, err := t.ReferenceFetcher.FetchReference(ctx, reference)
 	if err != nil {
 		return ocispec.Descriptor{}, nil, err
 	}
-	if exists {
-		err = rc.Close()
-		if err != nil {
-			return ocispec.Descriptor{}, nil, err
-		}
+	t.storeTag(ctx, reference, target)
 
-		// get rc from the cache
-		rc, err = t.cache.Fetch(ctx, target)
-		if err != nil {
+	// skip caching if the content already exists in cache
+	cached, err := t.fetchCached(ctx, target)
+	if err == nil {
+		if err := rc.Close(); err != nil {
+			cached.Close()
 			return ocispec.Descriptor{}, nil, err
 		}
 
 		// no need to do tee'd push
-		return target, rc, nil
+		return target, cached, nil
+	}
+	if !errors.Is(err, errdef.ErrNotFound) && !errors.Is(err, cache.ErrCorrupted) {
+		rc.Close()
+		return ocispec.Descriptor{}, nil, err
 	}
 
 	// Fetch from origin with caching
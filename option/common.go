@@ -21,16 +21,44 @@ type Common struct {
 	Debug   bool
 	Verbose bool
 	TTY     *os.File
+	// EventHandler handles the transfer and status events of an operation.
+	// If not set, progress bars are rendered to TTY, events are printed as
+	// text to TTY if the progress output is disabled, and events are
+	// discarded if TTY is not set.
+	EventHandler display.EventHandler
 
 	// [Preview] do not show progress output
 	noTTY bool
 }
 
+// DisableTTY disables the progress output on the terminal.
+func (opts *Common) DisableTTY() {
+	opts.noTTY = true
+}
+
 // WithContext returns a new FieldLogger and an associated Context derived from ctx.
 func (opts *Common) WithContext(ctx context.Context) (context.Context, *slog.Logger) {
 	return display.NewLogger(ctx, true)
 }
 
+// Events returns the handler for transfer events and a function to be called
+// once the operation is done. Progress bars are rendered if TTY is set, and
+// events are printed as text instead if the progress output is disabled.
+// Events are discarded if there is no TTY to write to.
+func (opts *Common) Events() (display.EventHandler, func()) {
+	if opts.EventHandler != nil {
+		return opts.EventHandler, func() {}
+	}
+	if opts.TTY == nil {
+		return display.NopHandler{}, func() {}
+	}
+	if opts.noTTY {
+		return display.NewTextHandler(opts.TTY, opts.Verbose), func() {}
+	}
+	handler := display.NewProgressHandler(opts.TTY, opts.Verbose)
+	return handler, handler.Stop
+}
+
 // Parse gets target options from user input.
 func (opts *Common) Parse() error {
 	// use STDERR as TTY output since STDOUT is reserved for pipeable output
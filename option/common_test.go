@@ -0,0 +1,39 @@
+package option
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/display"
+)
+
+func TestCommon_Events(t *testing.T) {
+	tty, err := os.Create(filepath.Join(t.TempDir(), "tty"))
+	require.NoError(t, err)
+	defer tty.Close()
+
+	var opts Common
+	handler, done := opts.Events()
+	done()
+	assert.Equal(t, display.NopHandler{}, handler)
+
+	opts.TTY = tty
+	handler, done = opts.Events()
+	done()
+	assert.IsType(t, &display.ProgressHandler{}, handler)
+
+	// events are printed as text if the progress output is disabled
+	opts.DisableTTY()
+	handler, done = opts.Events()
+	done()
+	assert.IsType(t, &display.TextHandler{}, handler)
+
+	opts.EventHandler = display.NewJSONHandler(tty)
+	handler, done = opts.Events()
+	done()
+	assert.Same(t, opts.EventHandler, handler)
+}
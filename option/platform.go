@@ -15,7 +15,14 @@ type Platform struct {
 	Platform *ocispec.Platform
 }
 
-// parse parses the input platform flag to an oci platform type.
+// SetPlatform sets the requested platform in the form of
+// `os[/arch][/variant][:os_version]`. It is validated when the option is
+// parsed.
+func (opts *Platform) SetPlatform(platform string) {
+	opts.platform = platform
+}
+
+// Parse parses the input platform flag to an oci platform type.
 func (opts *Platform) Parse() error {
 	if opts.platform == "" {
 		return nil
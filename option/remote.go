@@ -1,7 +1,6 @@
 package option
 
 import (
-	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -25,9 +24,22 @@ import (
 // Remote options struct.
 type Remote struct {
 	DistributionSpec
-	CACertFilePath    string
-	Insecure          bool
-	Configs           []string
+	// CACertFilePath and Insecure are kept for compatibility and folded into
+	// TLSProfile, taking precedence over its fields if set.
+	CACertFilePath string
+	Insecure       bool
+	// TLSProfile is the TLS settings used for registries without a profile in
+	// TLSProfiles.
+	TLSProfile
+	// TLSProfiles are the TLS settings keyed by registry host, with or without
+	// port. A profile replaces TLSProfile as a whole.
+	TLSProfiles map[string]TLSProfile
+	Configs     []string
+	// CredentialStore provides the credentials of registries if no username
+	// and password are given. The environment variables described in
+	// credential.NewEnvStore and then the docker config files in Configs are
+	// used if not set.
+	CredentialStore   credential.Store
 	Username          string
 	PasswordFromStdin bool
 	Password          string
@@ -55,6 +67,27 @@ func (opts *Remote) EnableDistributionSpecFlag() {
 	opts.applyDistributionSpec = true
 }
 
+// AddHeader adds a custom header sent with each request. It is validated when
+// the option is parsed.
+func (opts *Remote) AddHeader(name, value string) {
+	opts.headerFlags = append(opts.headerFlags, name+":"+value)
+}
+
+// AddResolve adds a custom DNS resolution in the form of
+// `host:port:address[:address_port]`. It is validated when the option is
+// parsed.
+func (opts *Remote) AddResolve(resolve string) {
+	opts.resolveFlag = append(opts.resolveFlag, resolve)
+}
+
+// SetPlainHTTP enforces or disables plain HTTP. Without it, plain HTTP is only
+// used for localhost.
+func (opts *Remote) SetPlainHTTP(plainHTTP bool) {
+	opts.plainHTTP = func() (bool, bool) {
+		return plainHTTP, true
+	}
+}
+
 func applyPrefix(prefix, description string) (flagPrefix, notePrefix string) {
 	if prefix == "" {
 		return "", ""
@@ -67,6 +100,10 @@ func (opts *Remote) Parse() error {
 	if err := opts.parseCustomHeaders(); err != nil {
 		return err
 	}
+	// validate resolve flags early, the dialer is assembled per client
+	if _, err := opts.parseResolve(nil); err != nil {
+		return err
+	}
 	if err := opts.readPassword(); err != nil {
 		return err
 	}
@@ -131,17 +168,18 @@ func (opts *Remote) parseResolve(baseDial onet.DialFunc) (onet.DialFunc, error)
 	return dialer.DialContext, nil
 }
 
-// tlsConfig assembles the tls config.
-func (opts *Remote) tlsConfig() (*tls.Config, error) {
-	config := &tls.Config{
-		InsecureSkipVerify: opts.Insecure,
+// tlsConfig assembles the tls config of the registry.
+func (opts *Remote) tlsConfig(registry string, logger *slog.Logger) (*tls.Config, error) {
+	config, err := opts.tlsProfile(registry).tlsConfig(logger.With("registry", registry))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS settings for %s: %w", registry, err)
 	}
 	return config, nil
 }
 
 // authClient assembles a oras auth client.
-func (opts *Remote) authClient(registry string, debug bool) (client *auth.Client, err error) {
-	config, err := opts.tlsConfig()
+func (opts *Remote) authClient(registry string, debug bool, logger *slog.Logger) (client *auth.Client, err error) {
+	config, err := opts.tlsConfig(registry, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -165,21 +203,30 @@ func (opts *Remote) authClient(registry string, debug bool) (client *auth.Client
 		client.Client.Transport = newTransport(client.Client.Transport)
 	}
 
-	cred := opts.Credential()
-	if cred != auth.EmptyCredential {
-		client.Credential = func(ctx context.Context, s string) (auth.Credential, error) {
-			return cred, nil
-		}
-	} else {
-		store, err := credential.NewStore(opts.Configs...)
-		if err != nil {
-			return nil, err
-		}
-		client.Credential = credentials.Credential(store)
+	store, err := opts.credentialStore()
+	if err != nil {
+		return nil, err
 	}
+	client.Credential = credentials.Credential(store)
 	return
 }
 
+// credentialStore assembles the credential store, which returns the given
+// username and password if any.
+func (opts *Remote) credentialStore() (credential.Store, error) {
+	if cred := opts.Credential(); cred != auth.EmptyCredential {
+		return credential.NewStaticStore(cred), nil
+	}
+	if opts.CredentialStore != nil {
+		return opts.CredentialStore, nil
+	}
+	store, err := credential.NewStore(opts.Configs...)
+	if err != nil {
+		return nil, err
+	}
+	return credential.Chain{credential.NewEnvStore(), store}, nil
+}
+
 func (opts *Remote) parseCustomHeaders() error {
 	if len(opts.headerFlags) != 0 {
 		headers := map[string][]string{}
@@ -235,7 +282,7 @@ func (opts *Remote) NewRegistry(
 	registry = reg.Reference.Registry
 	reg.PlainHTTP = opts.isPlainHttp(registry)
 	reg.HandleWarning = opts.handleWarning(registry, logger)
-	if reg.Client, err = opts.authClient(registry, common.Debug); err != nil {
+	if reg.Client, err = opts.authClient(registry, common.Debug, logger); err != nil {
 		return nil, err
 	}
 	return
@@ -254,7 +301,7 @@ func (opts *Remote) NewRepository(
 	registry := repo.Reference.Registry
 	repo.PlainHTTP = opts.isPlainHttp(registry)
 	repo.HandleWarning = opts.handleWarning(registry, logger)
-	if repo.Client, err = opts.authClient(registry, common.Debug); err != nil {
+	if repo.Client, err = opts.authClient(registry, common.Debug, logger); err != nil {
 		return nil, err
 	}
 	repo.SkipReferrersGC = true
@@ -268,7 +315,10 @@ func (opts *Remote) NewRepository(
 
 // isPlainHttp returns the plain http flag for a given registry.
 func (opts *Remote) isPlainHttp(registry string) bool {
-	plainHTTP, enforced := opts.plainHTTP()
+	var plainHTTP, enforced bool
+	if opts.plainHTTP != nil {
+		plainHTTP, enforced = opts.plainHTTP()
+	}
 	if enforced {
 		return plainHTTP
 	}
@@ -14,11 +14,18 @@ type DistributionSpec struct {
 	specFlag string
 }
 
-// Parse parses flags into the option.
+// SetDistributionSpec sets the distribution specification in the form of
+// `<version>-<api>-<option>`, e.g. `v1.1-referrers-api` or
+// `v1.1-referrers-tag`. It is validated when the option is parsed.
+func (opts *DistributionSpec) SetDistributionSpec(spec string) {
+	opts.specFlag = spec
+}
+
+// Parse parses flags into the option. ReferrersAPI is left untouched if no
+// specification is set.
 func (opts *DistributionSpec) Parse() error {
 	switch opts.specFlag {
 	case "":
-		opts.ReferrersAPI = nil
 	case "v1.1-referrers-tag":
 		isApi := false
 		opts.ReferrersAPI = &isApi
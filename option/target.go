@@ -8,10 +8,14 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/opencontainers/go-digest"
 	"golang.org/x/exp/slog"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content/oci"
 	"oras.land/oras-go/v2/registry"
+
+	"github.com/koolay/oras-sdk/fileref"
+	"github.com/koolay/oras-sdk/version"
 )
 
 const (
@@ -30,6 +34,14 @@ type Target struct {
 	//  - path to the OCI image layout target, or
 	//  - registry and repository for the remote target
 	Path string
+	// VersionConstraint selects the tag of the newest semantic version
+	// satisfying the constraint, e.g. `^1.4` or `>=2.0 <3`, in place of a tag
+	// in RawReference. See version.Select for details. It cannot be used
+	// with blobs or with the destination of a copy.
+	VersionConstraint string
+	// IncludePrerelease includes prerelease versions when selecting the tag by
+	// VersionConstraint.
+	IncludePrerelease bool
 
 	isOCILayout bool
 }
@@ -39,10 +51,16 @@ func (opts *Target) AnnotatedReference() string {
 	return fmt.Sprintf("[%s] %s", opts.Type, opts.RawReference)
 }
 
+// DisplayReference returns the printable reference of the content identified
+// by the digest in the target.
+func (opts *Target) DisplayReference(digest digest.Digest) string {
+	return fmt.Sprintf("[%s] %s@%s", opts.Type, opts.Path, digest)
+}
+
 // Parse gets target options from user input.
 func (opts *Target) Parse() error {
 	switch {
-	case opts.isOCILayout:
+	case opts.isOCILayout, opts.Type == TargetTypeOCILayout:
 		opts.Type = TargetTypeOCILayout
 		if len(opts.headerFlags) != 0 {
 			return errors.New("custom header flags cannot be used on an OCI image layout target")
@@ -62,7 +80,7 @@ func parseOCILayoutReference(raw string) (path string, ref string, err error) {
 		ref = raw[idx+1:]
 	} else {
 		// find `tag`
-		path, ref, err = parseFileRef(raw, "")
+		path, ref, err = fileref.Parse(raw, "")
 	}
 	return
 }
@@ -91,6 +109,21 @@ func (opts *Target) NewTarget(common Common, logger *slog.Logger) (oras.GraphTar
 	return nil, fmt.Errorf("unknown target type: %q", opts.Type)
 }
 
+// NewExistingTarget generates a new target based on opts as NewTarget does,
+// but fails instead of creating an OCI image layout that does not exist.
+func (opts *Target) NewExistingTarget(common Common, logger *slog.Logger) (oras.GraphTarget, error) {
+	if opts.Type == TargetTypeOCILayout {
+		path, _, err := parseOCILayoutReference(opts.RawReference)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+	return opts.NewTarget(common, logger)
+}
+
 // ReadOnlyGraphTagFinderTarget represents a read-only graph target with tag
 // finder capability.
 type ReadOnlyGraphTagFinderTarget interface {
@@ -133,6 +166,46 @@ func (opts *Target) NewReadonlyTarget(
 	return nil, fmt.Errorf("unknown target type: %q", opts.Type)
 }
 
+// ResolveVersion selects the tag from the tags of lister by VersionConstraint
+// and sets it as the reference of opts. It is a no-op if VersionConstraint is
+// not set.
+func (opts *Target) ResolveVersion(ctx context.Context, lister registry.TagLister) error {
+	if opts.VersionConstraint == "" {
+		return nil
+	}
+	if opts.Reference != "" {
+		return fmt.Errorf("version constraint %q cannot be used with tag or digest %q", opts.VersionConstraint, opts.Reference)
+	}
+	var tags []string
+	if err := lister.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	}); err != nil {
+		return err
+	}
+	tag, err := version.Select(tags, opts.VersionConstraint, opts.IncludePrerelease)
+	if err != nil {
+		return fmt.Errorf("%s: %w", opts.Path, err)
+	}
+	opts.Reference = tag
+	opts.RawReference = opts.Path + ":" + tag
+	return nil
+}
+
+// ResolveTargetVersion selects the tag like ResolveVersion, listing the tags
+// of target. An error is returned if VersionConstraint is set but target
+// cannot list tags.
+func (opts *Target) ResolveTargetVersion(ctx context.Context, target oras.ReadOnlyTarget) error {
+	if opts.VersionConstraint == "" {
+		return nil
+	}
+	lister, ok := target.(registry.TagLister)
+	if !ok {
+		return fmt.Errorf("version constraint %q is not supported by %s", opts.VersionConstraint, opts.Type)
+	}
+	return opts.ResolveVersion(ctx, lister)
+}
+
 // EnsureReferenceNotEmpty ensures whether the tag or digest is empty.
 func (opts *Target) EnsureReferenceNotEmpty() error {
 	if opts.Reference == "" {
@@ -155,26 +228,66 @@ func (opts *BinaryTarget) EnableDistributionSpecFlag() {
 	opts.To.EnableDistributionSpecFlag()
 }
 
+// AddHeader adds a custom header sent with each request to both targets.
+func (opts *BinaryTarget) AddHeader(name, value string) {
+	opts.From.AddHeader(name, value)
+	opts.To.AddHeader(name, value)
+}
+
+// AddResolve adds a custom DNS resolution applied to both targets.
+func (opts *BinaryTarget) AddResolve(resolve string) {
+	opts.resolveFlag = append(opts.resolveFlag, resolve)
+}
+
+// SetPlainHTTP enforces or disables plain HTTP for both targets.
+func (opts *BinaryTarget) SetPlainHTTP(plainHTTP bool) {
+	opts.From.SetPlainHTTP(plainHTTP)
+	opts.To.SetPlainHTTP(plainHTTP)
+}
+
+// SetDistributionSpec sets the distribution specification of both targets.
+func (opts *BinaryTarget) SetDistributionSpec(spec string) {
+	opts.From.SetDistributionSpec(spec)
+	opts.To.SetDistributionSpec(spec)
+}
+
 // Parse parses user-provided flags and arguments into option struct.
 func (opts *BinaryTarget) Parse() error {
 	opts.From.warned = make(map[string]*sync.Map)
 	opts.To.warned = opts.From.warned
 	// resolve are parsed in array order, latter will overwrite former
-	opts.From.resolveFlag = append(opts.resolveFlag, opts.From.resolveFlag...)
-	opts.To.resolveFlag = append(opts.resolveFlag, opts.To.resolveFlag...)
+	opts.From.resolveFlag = append(append([]string{}, opts.resolveFlag...), opts.From.resolveFlag...)
+	opts.To.resolveFlag = append(append([]string{}, opts.resolveFlag...), opts.To.resolveFlag...)
 	return Parse(opts)
 }
 
-// parseFileRef parses file reference on unix.
-func parseFileRef(reference string, defaultMetadata string) (filePath, metadata string, err error) {
-	i := strings.LastIndex(reference, ":")
-	if i < 0 {
-		filePath, metadata = reference, defaultMetadata
-	} else {
-		filePath, metadata = reference[:i], reference[i+1:]
+// NewTargets generates the source and the destination targets based on opts.
+// The warning records are shared between the two targets.
+func (opts *BinaryTarget) NewTargets(
+	ctx context.Context,
+	common Common,
+	logger *slog.Logger,
+) (ReadOnlyGraphTagFinderTarget, oras.GraphTarget, error) {
+	if opts.From.warned == nil {
+		opts.From.warned = make(map[string]*sync.Map)
+	}
+	opts.To.warned = opts.From.warned
+	src, err := opts.From.NewReadonlyTarget(ctx, common, logger)
+	if err != nil {
+		return nil, nil, err
 	}
-	if filePath == "" {
-		return "", "", fmt.Errorf("found empty file path in %q", reference)
+	dst, err := opts.To.NewTarget(common, logger)
+	if err != nil {
+		return nil, nil, err
 	}
-	return filePath, metadata, nil
+	return src, dst, nil
+}
+
+// EnsureSourceTargetReferenceNotEmpty ensures that the from target reference
+// is not empty.
+func (opts *BinaryTarget) EnsureSourceTargetReferenceNotEmpty() error {
+	if opts.From.Reference == "" {
+		return errors.New("missing source reference")
+	}
+	return nil
 }
@@ -0,0 +1,205 @@
+package option
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// tlsVersions maps the supported minimum TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSProfile describes the TLS settings used to connect to a registry.
+type TLSProfile struct {
+	// CACertFilePath is the path to a PEM bundle of CA certificates trusted in
+	// addition to the system certificate pool.
+	CACertFilePath string
+	// Insecure skips the verification of the server certificate.
+	Insecure bool
+	// CertFilePath and KeyFilePath are the paths to the PEM encoded client
+	// certificate and key for mutual TLS.
+	CertFilePath string
+	KeyFilePath  string
+	// PKCS12FilePath is the path to a PKCS#12 bundle of the client
+	// certificate and key, decrypted with PKCS12Password. It cannot be used
+	// together with CertFilePath.
+	PKCS12FilePath string
+	PKCS12Password string
+	// MinTLSVersion is the minimum TLS version, one of `1.0`, `1.1`, `1.2`
+	// and `1.3`. The default of crypto/tls is used if empty.
+	MinTLSVersion string
+}
+
+// tlsConfig assembles the tls config of the profile. Skipped CA certificates
+// are reported to logger.
+func (p TLSProfile) tlsConfig(logger *slog.Logger) (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: p.Insecure,
+	}
+	if p.MinTLSVersion != "" {
+		version, ok := tlsVersions[p.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q: expecting one of 1.0, 1.1, 1.2 and 1.3", p.MinTLSVersion)
+		}
+		config.MinVersion = version
+	}
+	if p.CACertFilePath != "" {
+		pool, err := loadCertPool(p.CACertFilePath, logger)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	cert, err := p.clientCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	return config, nil
+}
+
+// clientCertificate loads the client certificate if specified.
+func (p TLSProfile) clientCertificate() (*tls.Certificate, error) {
+	switch {
+	case p.PKCS12FilePath != "":
+		if p.CertFilePath != "" || p.KeyFilePath != "" {
+			return nil, errors.New("client certificate and PKCS#12 bundle cannot be used together")
+		}
+		data, err := os.ReadFile(p.PKCS12FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PKCS#12 file: %w", err)
+		}
+		key, leaf, chain, err := pkcs12.DecodeChain(data, p.PKCS12Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode PKCS#12 file %q: %w", p.PKCS12FilePath, err)
+		}
+		cert := &tls.Certificate{
+			Certificate: [][]byte{leaf.Raw},
+			PrivateKey:  key,
+			Leaf:        leaf,
+		}
+		for _, c := range chain {
+			cert.Certificate = append(cert.Certificate, c.Raw)
+		}
+		if err := checkValidity(leaf, p.PKCS12FilePath); err != nil {
+			return nil, err
+		}
+		return cert, nil
+	case p.CertFilePath != "" || p.KeyFilePath != "":
+		if p.CertFilePath == "" || p.KeyFilePath == "" {
+			return nil, errors.New("client certificate and key must be specified together")
+		}
+		certPEM, err := os.ReadFile(p.CertFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		keyPEM, err := os.ReadFile(p.KeyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate %q: %w", p.CertFilePath, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse client certificate %q: %w", p.CertFilePath, err)
+		}
+		if err := checkValidity(leaf, p.CertFilePath); err != nil {
+			return nil, err
+		}
+		cert.Leaf = leaf
+		return &cert, nil
+	}
+	return nil, nil
+}
+
+// loadCertPool loads the PEM bundle of CA certificates into the system
+// certificate pool. Expired or not yet valid certificates are skipped with a
+// warning to logger, and an error is returned only if none of the
+// certificates is valid.
+func loadCertPool(path string, logger *slog.Logger) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	var count int
+	var invalidErr error
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CA certificate in %q: %w", path, err)
+		}
+		if err := checkValidity(cert, path); err != nil {
+			logger.Warn("skipping CA certificate", "error", err)
+			if invalidErr == nil {
+				invalidErr = err
+			}
+			continue
+		}
+		pool.AddCert(cert)
+		count++
+	}
+	if count == 0 {
+		if invalidErr != nil {
+			return nil, invalidErr
+		}
+		return nil, fmt.Errorf("no PEM encoded certificate found in CA certificate file %q", path)
+	}
+	return pool, nil
+}
+
+// checkValidity returns an error if cert is expired or not yet valid.
+func checkValidity(cert *x509.Certificate, path string) error {
+	now := time.Now()
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate %q in %q expired at %s", cert.Subject, path, cert.NotAfter.Format(time.RFC3339))
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("certificate %q in %q is not valid before %s", cert.Subject, path, cert.NotBefore.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// tlsProfile returns the TLS profile of the registry, looked up by
+// `host:port` and then by host, or the default profile if not found.
+func (opts *Remote) tlsProfile(registry string) TLSProfile {
+	if profile, ok := opts.TLSProfiles[registry]; ok {
+		return profile
+	}
+	if host, _, err := net.SplitHostPort(registry); err == nil {
+		if profile, ok := opts.TLSProfiles[host]; ok {
+			return profile
+		}
+	}
+	profile := opts.TLSProfile
+	if opts.CACertFilePath != "" {
+		profile.CACertFilePath = opts.CACertFilePath
+	}
+	if opts.Insecure {
+		profile.Insecure = true
+	}
+	return profile
+}
@@ -0,0 +1,217 @@
+package artifacts
+
+import (
+	"fmt"
+
+	"github.com/koolay/oras-sdk/option"
+)
+
+// defaultConcurrency is the default number of concurrent requests, the same
+// as the default of oras.
+const defaultConcurrency = 3
+
+// Option configures the options of an operation. Options are applied by the
+// New*Options constructors before the options are parsed.
+type Option func(opts any) error
+
+type headerSetter interface {
+	AddHeader(name, value string)
+}
+
+type resolveSetter interface {
+	AddResolve(resolve string)
+}
+
+type plainHTTPSetter interface {
+	SetPlainHTTP(plainHTTP bool)
+}
+
+type distributionSpecSetter interface {
+	SetDistributionSpec(spec string)
+}
+
+type platformSetter interface {
+	SetPlatform(platform string)
+}
+
+type concurrencySetter interface {
+	setConcurrency(n int)
+}
+
+// WithHeader adds a custom header sent with each request to the registry.
+func WithHeader(name, value string) Option {
+	return func(opts any) error {
+		s, ok := opts.(headerSetter)
+		if !ok {
+			return unsupportedOption(opts, "custom headers")
+		}
+		s.AddHeader(name, value)
+		return nil
+	}
+}
+
+// WithResolve adds a custom DNS resolution in the form of
+// `host:port:address[:address_port]`, e.g. `localhost:5000:127.0.0.1:5001`.
+func WithResolve(resolve string) Option {
+	return func(opts any) error {
+		s, ok := opts.(resolveSetter)
+		if !ok {
+			return unsupportedOption(opts, "custom DNS resolution")
+		}
+		s.AddResolve(resolve)
+		return nil
+	}
+}
+
+// WithPlainHTTP enforces or disables plain HTTP connections to the registry.
+// Without it, plain HTTP is only used for localhost.
+func WithPlainHTTP(plainHTTP bool) Option {
+	return func(opts any) error {
+		s, ok := opts.(plainHTTPSetter)
+		if !ok {
+			return unsupportedOption(opts, "plain HTTP")
+		}
+		s.SetPlainHTTP(plainHTTP)
+		return nil
+	}
+}
+
+// WithDistributionSpec sets the distribution specification in the form of
+// `<version>-<api>-<option>`, i.e. `v1.1-referrers-api` or
+// `v1.1-referrers-tag`.
+func WithDistributionSpec(spec string) Option {
+	return func(opts any) error {
+		s, ok := opts.(distributionSpecSetter)
+		if !ok {
+			return unsupportedOption(opts, "distribution specification")
+		}
+		s.SetDistributionSpec(spec)
+		return nil
+	}
+}
+
+// WithPlatform requests the platform in the form of
+// `os[/arch][/variant][:os_version]`, e.g. `linux/arm64`.
+func WithPlatform(platform string) Option {
+	return func(opts any) error {
+		s, ok := opts.(platformSetter)
+		if !ok {
+			return unsupportedOption(opts, "platform")
+		}
+		s.SetPlatform(platform)
+		return nil
+	}
+}
+
+// WithConcurrency sets the number of concurrent transfers.
+func WithConcurrency(n int) Option {
+	return func(opts any) error {
+		if n < 1 {
+			return fmt.Errorf("invalid concurrency %d: must be positive", n)
+		}
+		s, ok := opts.(concurrencySetter)
+		if !ok {
+			return unsupportedOption(opts, "concurrency")
+		}
+		s.setConcurrency(n)
+		return nil
+	}
+}
+
+// WithNoTTY disables the progress output on the terminal.
+func WithNoTTY() Option {
+	return func(opts any) error {
+		s, ok := opts.(interface{ DisableTTY() })
+		if !ok {
+			return unsupportedOption(opts, "TTY")
+		}
+		s.DisableTTY()
+		return nil
+	}
+}
+
+func unsupportedOption(opts any, name string) error {
+	return fmt.Errorf("%T does not support %s", opts, name)
+}
+
+// applyOptions applies the options to optsPtr and parses it.
+func applyOptions(optsPtr any, options []Option) error {
+	for _, o := range options {
+		if err := o(optsPtr); err != nil {
+			return err
+		}
+	}
+	return option.Parse(optsPtr)
+}
+
+// NewPullOptions returns the options pulling from the reference, which is a
+// registry reference unless the target type is set to an OCI image layout.
+func NewPullOptions(reference string, options ...Option) (PullOptions, error) {
+	opts := PullOptions{}
+	opts.RawReference = reference
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+// NewPushOptions returns the options pushing the files to the reference.
+func NewPushOptions(reference string, fileRefs []string, options ...Option) (PushOptions, error) {
+	opts := PushOptions{
+		FileRefs: fileRefs,
+	}
+	opts.RawReference = reference
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+// NewCopyOptions returns the options copying from the source reference to the
+// destination reference.
+func NewCopyOptions(from, to string, options ...Option) (CopyOptions, error) {
+	opts := CopyOptions{}
+	opts.From.RawReference = from
+	opts.To.RawReference = to
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+// NewTagOptions returns the options tagging the manifest referenced by the
+// reference with the tags.
+func NewTagOptions(reference string, tags []string, options ...Option) (TagOptions, error) {
+	opts := TagOptions{
+		Tags: tags,
+	}
+	opts.RawReference = reference
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+// NewListTagsOptions returns the options listing the tags of the repository
+// of the reference.
+func NewListTagsOptions(reference string, options ...Option) (ListTagsOptions, error) {
+	opts := ListTagsOptions{}
+	opts.RawReference = reference
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+// NewPushManifestOptions returns the options pushing the manifest to the
+// reference. The manifest may be nil if FilePath is set instead.
+func NewPushManifestOptions(reference string, manifest []byte, options ...Option) (PushManifestOptions, error) {
+	opts := PushManifestOptions{
+		Content: manifest,
+	}
+	opts.RawReference = reference
+	err := applyOptions(&opts, options)
+	return opts, err
+}
+
+func (opts *PullOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
+
+func (opts *PushOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
+
+func (opts *CopyOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
@@ -0,0 +1,115 @@
+package artifacts
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestNewPullOptions(t *testing.T) {
+	opts, err := NewPullOptions("localhost:5000/hello:v1",
+		WithPlatform("linux/arm64/v8"),
+		WithConcurrency(5),
+		WithDistributionSpec("v1.1-referrers-tag"),
+		WithHeader("X-Test", "value"),
+		WithNoTTY(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000/hello:v1", opts.RawReference)
+	require.NotNil(t, opts.Platform.Platform)
+	assert.Equal(t, "linux", opts.Platform.Platform.OS)
+	assert.Equal(t, "arm64", opts.Platform.Platform.Architecture)
+	assert.Equal(t, "v8", opts.Platform.Platform.Variant)
+	assert.Equal(t, 5, opts.concurrency)
+	require.NotNil(t, opts.ReferrersAPI)
+	assert.False(t, *opts.ReferrersAPI)
+	assert.Nil(t, opts.TTY)
+
+	for name, o := range map[string]Option{
+		"platform":    WithPlatform("/arm64"),
+		"concurrency": WithConcurrency(0),
+		"spec":        WithDistributionSpec("v1.0"),
+		"header":      WithHeader("", "value"),
+		"resolve":     WithResolve("localhost:5000"),
+	} {
+		_, err := NewPullOptions("localhost:5000/hello:v1", o)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestNewCopyOptions(t *testing.T) {
+	opts, err := NewCopyOptions("localhost:5000/a:v1", "localhost:5000/b:v1",
+		WithConcurrency(2),
+		WithDistributionSpec("v1.1-referrers-api"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, opts.concurrency)
+	require.NotNil(t, opts.From.ReferrersAPI)
+	assert.True(t, *opts.To.ReferrersAPI)
+}
+
+func TestNewTagOptions(t *testing.T) {
+	opts, err := NewTagOptions("localhost:5000/hello:v1", []string{"v2", "latest"},
+		WithConcurrency(2),
+		WithPlainHTTP(true),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000/hello:v1", opts.RawReference)
+	assert.Equal(t, []string{"v2", "latest"}, opts.Tags)
+	assert.Equal(t, 2, opts.concurrency)
+
+	_, err = NewTagOptions("localhost:5000/hello:v1", nil, WithPlatform("linux/amd64"))
+	assert.NotNil(t, err)
+}
+
+func TestNewListTagsOptions(t *testing.T) {
+	opts, err := NewListTagsOptions("localhost:5000/hello", WithConcurrency(4))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000/hello", opts.RawReference)
+	assert.Equal(t, 4, opts.concurrency)
+}
+
+func TestNewPushManifestOptions(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2}`)
+	opts, err := NewPushManifestOptions("localhost:5000/hello:v1", manifest, WithConcurrency(2))
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:5000/hello:v1", opts.RawReference)
+	assert.Equal(t, manifest, opts.Content)
+	assert.Equal(t, 2, opts.concurrency)
+}
+
+func TestNewPullOptions_resolve(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "hello.txt", MediaType: "text/plain", Content: []byte("hello")},
+		},
+	})
+	_, port, err := net.SplitHostPort(reg.Host)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	opts, err := NewPullOptions("registry.test:"+port+"/hello:v1",
+		WithResolve("registry.test:"+port+":127.0.0.1"),
+		WithPlainHTTP(true),
+		WithHeader("X-Test", "value"),
+	)
+	require.NoError(t, err)
+	opts.Output = dir
+	opts.EventHandler = display.NopHandler{}
+	_, err = RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join(dir, "hello.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(got))
+}
@@ -0,0 +1,50 @@
+package orastest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// credentialHelper is a docker credential helper saving credentials as files
+// next to itself.
+const credentialHelper = `#!/bin/sh
+dir="$(dirname "$0")/store"
+mkdir -p "$dir"
+key() {
+	printf '%s' "$1" | tr -c 'A-Za-z0-9.' '_'
+}
+case "$1" in
+get)
+	read -r server
+	if [ -f "$dir/$(key "$server")" ]; then
+		cat "$dir/$(key "$server")"
+	else
+		echo "credentials not found in native keychain"
+		exit 1
+	fi
+	;;
+store)
+	payload=$(cat)
+	server=$(printf '%s' "$payload" | sed 's/.*"ServerURL":"\([^"]*\)".*/\1/')
+	printf '%s' "$payload" > "$dir/$(key "$server")"
+	;;
+erase)
+	read -r server
+	rm -f "$dir/$(key "$server")"
+	;;
+esac
+`
+
+// InstallCredentialHelper installs a docker credential helper as
+// `docker-credential-<suffix>` in PATH until the test finishes. The helper
+// keeps the credentials in a temporary directory.
+func InstallCredentialHelper(t testing.TB, suffix string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-credential-"+suffix)
+	if err := os.WriteFile(path, []byte(credentialHelper), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
@@ -0,0 +1,670 @@
+// Package orastest provides an in-memory OCI distribution registry served by
+// an httptest.Server for testing.
+package orastest
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Error codes defined by the distribution specification.
+const (
+	codeBlobUnknown     = "BLOB_UNKNOWN"
+	codeDigestInvalid   = "DIGEST_INVALID"
+	codeManifestUnknown = "MANIFEST_UNKNOWN"
+	codeNameUnknown     = "NAME_UNKNOWN"
+	codeUnauthorized    = "UNAUTHORIZED"
+	codeUnsupported     = "UNSUPPORTED"
+)
+
+// tokenService is the service name of the token auth challenge.
+const tokenService = "orastest"
+
+// manifest is a manifest stored in a repository.
+type manifest struct {
+	mediaType string
+	content   []byte
+}
+
+// repository is an in-memory repository.
+type repository struct {
+	blobs     map[digest.Digest][]byte
+	manifests map[digest.Digest]manifest
+	tags      map[string]digest.Digest
+	uploads   map[string][]byte
+}
+
+func newRepository() *repository {
+	return &repository{
+		blobs:     make(map[digest.Digest][]byte),
+		manifests: make(map[digest.Digest]manifest),
+		tags:      make(map[string]digest.Digest),
+		uploads:   make(map[string][]byte),
+	}
+}
+
+// Failure describes a failure to be injected into matching requests.
+type Failure struct {
+	// Method matches the request method if not empty.
+	Method string
+	// Path matches requests whose path contains it if not empty.
+	Path string
+	// Status is the status code responded.
+	Status int
+	// Times is the number of requests to fail. Requests fail forever if it is
+	// less than or equal to 0.
+	Times int
+}
+
+// Registry is an in-memory OCI distribution registry.
+type Registry struct {
+	*httptest.Server
+	// Host is the `host:port` of the registry.
+	Host string
+
+	lock         sync.Mutex
+	repos        map[string]*repository
+	username     string
+	password     string
+	refreshToken string
+	tokens       map[string]bool
+	noReferrers  bool
+	pageSize     int
+	tls          bool
+	clientCAs    *x509.CertPool
+	failures     []*Failure
+	requests     []string
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithBasicAuth requires clients to authenticate with username and password
+// through a token auth challenge.
+func WithBasicAuth(username, password string) Option {
+	return func(r *Registry) {
+		r.username = username
+		r.password = password
+	}
+}
+
+// WithRefreshToken requires clients to authenticate with an identity token
+// through a token auth challenge.
+func WithRefreshToken(token string) Option {
+	return func(r *Registry) {
+		r.refreshToken = token
+	}
+}
+
+// WithoutReferrersAPI disables the referrers API so that clients fall back
+// to the referrers tag schema.
+func WithoutReferrersAPI() Option {
+	return func(r *Registry) {
+		r.noReferrers = true
+	}
+}
+
+// WithPageSize paginates the repository and tag lists by size if clients
+// do not request a page size.
+func WithPageSize(size int) Option {
+	return func(r *Registry) {
+		r.pageSize = size
+	}
+}
+
+// WithTLS serves the registry over TLS with a self-signed certificate, which
+// can be obtained from Certificate. Clients are required to present a
+// certificate signed by one of clientCAs if it is not nil.
+func WithTLS(clientCAs *x509.CertPool) Option {
+	return func(r *Registry) {
+		r.tls = true
+		r.clientCAs = clientCAs
+	}
+}
+
+// NewRegistry starts a registry. The registry should be closed by the caller
+// when finished.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		repos:  make(map[string]*repository),
+		tokens: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.Server = httptest.NewUnstartedServer(http.HandlerFunc(r.serveHTTP))
+	if r.tls {
+		if r.clientCAs != nil {
+			r.Server.TLS = &tls.Config{
+				ClientCAs:  r.clientCAs,
+				ClientAuth: tls.RequireAndVerifyClientCert,
+			}
+		}
+		r.Server.StartTLS()
+	} else {
+		r.Server.Start()
+	}
+	u, _ := url.Parse(r.Server.URL)
+	r.Host = u.Host
+	return r
+}
+
+// Reference returns the reference to the repository in the registry with the
+// tag or digest.
+func (r *Registry) Reference(repo, ref string) string {
+	if ref == "" {
+		return r.Host + "/" + repo
+	}
+	if _, err := digest.Parse(ref); err == nil {
+		return r.Host + "/" + repo + "@" + ref
+	}
+	return r.Host + "/" + repo + ":" + ref
+}
+
+// InjectFailure makes the matching requests fail.
+func (r *Registry) InjectFailure(f Failure) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.failures = append(r.failures, &f)
+}
+
+// Requests returns the served requests in the form of `METHOD path`.
+func (r *Registry) Requests() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]string(nil), r.requests...)
+}
+
+// ResetRequests clears the record of served requests.
+func (r *Registry) ResetRequests() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = nil
+}
+
+func (r *Registry) authEnabled() bool {
+	return r.username != "" || r.refreshToken != ""
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+
+	if status := r.injectedFailure(req); status != 0 {
+		writeError(w, status, codeUnsupported, "injected failure")
+		return
+	}
+	if req.URL.Path == "/token" {
+		r.serveToken(w, req)
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, "/v2/") {
+		http.NotFound(w, req)
+		return
+	}
+	if r.authEnabled() && !r.authorized(req) {
+		realm := r.Server.URL + "/token"
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q,service=%q", realm, tokenService))
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "authentication required")
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case path == "":
+		w.WriteHeader(http.StatusOK)
+	case path == "_catalog":
+		r.serveCatalog(w, req)
+	case strings.HasSuffix(path, "/tags/list"):
+		r.serveTags(w, req, strings.TrimSuffix(path, "/tags/list"))
+	case strings.Contains(path, "/manifests/"):
+		name, ref := cut(path, "/manifests/")
+		r.serveManifest(w, req, name, ref)
+	case strings.Contains(path, "/blobs/uploads/"):
+		name, id := cut(path, "/blobs/uploads/")
+		r.serveUpload(w, req, name, id)
+	case strings.Contains(path, "/blobs/"):
+		name, ref := cut(path, "/blobs/")
+		r.serveBlob(w, req, name, ref)
+	case strings.Contains(path, "/referrers/") && !r.noReferrers:
+		name, ref := cut(path, "/referrers/")
+		r.serveReferrers(w, req, name, ref)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// injectedFailure returns the status of the first failure matching req, or 0
+// if not matched.
+func (r *Registry) injectedFailure(req *http.Request) int {
+	for i, f := range r.failures {
+		if f.Method != "" && f.Method != req.Method {
+			continue
+		}
+		if f.Path != "" && !strings.Contains(req.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				r.failures = append(r.failures[:i], r.failures[i+1:]...)
+			}
+		}
+		return f.Status
+	}
+	return 0
+}
+
+func (r *Registry) authorized(req *http.Request) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && r.tokens[token]
+}
+
+// serveToken issues tokens for valid credentials.
+func (r *Registry) serveToken(w http.ResponseWriter, req *http.Request) {
+	var valid bool
+	switch req.Method {
+	case http.MethodGet:
+		username, password, ok := req.BasicAuth()
+		valid = ok && r.username != "" && username == r.username && password == r.password
+	case http.MethodPost:
+		if err := req.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, codeUnsupported, err.Error())
+			return
+		}
+		switch req.PostForm.Get("grant_type") {
+		case "refresh_token":
+			valid = r.refreshToken != "" && req.PostForm.Get("refresh_token") == r.refreshToken
+		case "password":
+			valid = r.username != "" &&
+				req.PostForm.Get("username") == r.username &&
+				req.PostForm.Get("password") == r.password
+		}
+	}
+	if !valid {
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "invalid credential")
+		return
+	}
+	token := randomID()
+	r.tokens[token] = true
+	writeJSON(w, "application/json", map[string]string{
+		"token":        token,
+		"access_token": token,
+	})
+}
+
+func (r *Registry) serveCatalog(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	names := make([]string, 0, len(r.repos))
+	for name := range r.repos {
+		names = append(names, name)
+	}
+	page, next := paginate(names, req.URL.Query(), r.pageSize)
+	if next != "" {
+		setLinkHeader(w, "/v2/_catalog", req.URL.Query(), next)
+	}
+	writeJSON(w, "application/json", map[string][]string{"repositories": page})
+}
+
+func (r *Registry) serveTags(w http.ResponseWriter, req *http.Request, name string) {
+	repo, ok := r.repos[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNameUnknown, name)
+		return
+	}
+	tags := make([]string, 0, len(repo.tags))
+	for tag := range repo.tags {
+		tags = append(tags, tag)
+	}
+	page, next := paginate(tags, req.URL.Query(), r.pageSize)
+	if next != "" {
+		setLinkHeader(w, "/v2/"+name+"/tags/list", req.URL.Query(), next)
+	}
+	writeJSON(w, "application/json", map[string]any{
+		"name": name,
+		"tags": page,
+	})
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if req.Method == http.MethodPut {
+		r.putManifest(w, req, name, ref)
+		return
+	}
+	repo, ok := r.repos[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNameUnknown, name)
+		return
+	}
+	dgst, ok := repo.resolve(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, codeManifestUnknown, ref)
+		return
+	}
+	m := repo.manifests[dgst]
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", m.mediaType)
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(m.content)))
+		w.WriteHeader(http.StatusOK)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(m.content)
+		}
+	case http.MethodDelete:
+		delete(repo.manifests, dgst)
+		for tag, d := range repo.tags {
+			if d == dgst {
+				delete(repo.tags, tag)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) putManifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	content, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeUnsupported, err.Error())
+		return
+	}
+	dgst := digest.FromBytes(content)
+	if d, err := digest.Parse(ref); err == nil && d != dgst {
+		writeError(w, http.StatusBadRequest, codeDigestInvalid, ref)
+		return
+	}
+	mediaType := req.Header.Get("Content-Type")
+	r.putManifestContent(name, ref, mediaType, content)
+	if subject := parseManifest(content).Subject; subject != nil && !r.noReferrers {
+		w.Header().Set("OCI-Subject", subject.Digest.String())
+	}
+	w.Header().Set("Docker-Content-Digest", dgst.String())
+	w.Header().Set("Location", "/v2/"+name+"/manifests/"+dgst.String())
+	w.WriteHeader(http.StatusCreated)
+}
+
+// putManifestContent stores the manifest content and tags it if ref is a tag.
+// The caller must hold the lock.
+func (r *Registry) putManifestContent(name, ref, mediaType string, content []byte) digest.Digest {
+	repo := r.repo(name)
+	dgst := digest.FromBytes(content)
+	repo.manifests[dgst] = manifest{
+		mediaType: mediaType,
+		content:   content,
+	}
+	if _, err := digest.Parse(ref); err != nil && ref != "" {
+		repo.tags[ref] = dgst
+	}
+	return dgst
+}
+
+func (r *Registry) serveBlob(w http.ResponseWriter, req *http.Request, name, ref string) {
+	repo, ok := r.repos[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNameUnknown, name)
+		return
+	}
+	dgst := digest.Digest(ref)
+	blob, ok := repo.blobs[dgst]
+	if !ok {
+		writeError(w, http.StatusNotFound, codeBlobUnknown, ref)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Docker-Content-Digest", dgst.String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		w.WriteHeader(http.StatusOK)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(blob)
+		}
+	case http.MethodDelete:
+		delete(repo.blobs, dgst)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request, name, id string) {
+	repo := r.repo(name)
+	query := req.URL.Query()
+	switch req.Method {
+	case http.MethodPost:
+		if mount := query.Get("mount"); mount != "" {
+			if from, ok := r.repos[query.Get("from")]; ok {
+				if blob, ok := from.blobs[digest.Digest(mount)]; ok {
+					repo.blobs[digest.Digest(mount)] = blob
+					w.Header().Set("Docker-Content-Digest", mount)
+					w.Header().Set("Location", "/v2/"+name+"/blobs/"+mount)
+					w.WriteHeader(http.StatusCreated)
+					return
+				}
+			}
+		}
+		if dgst := query.Get("digest"); dgst != "" {
+			// monolithic upload in a single POST
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, codeUnsupported, err.Error())
+				return
+			}
+			r.completeUpload(w, repo, name, dgst, body)
+			return
+		}
+		id = randomID()
+		repo.uploads[id] = nil
+		w.Header().Set("Location", r.Server.URL+"/v2/"+name+"/blobs/uploads/"+id)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPatch, http.MethodPut:
+		data, ok := repo.uploads[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", id)
+			return
+		}
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeUnsupported, err.Error())
+			return
+		}
+		data = append(data, body...)
+		if req.Method == http.MethodPatch {
+			repo.uploads[id] = data
+			w.Header().Set("Location", r.Server.URL+"/v2/"+name+"/blobs/uploads/"+id)
+			w.Header().Set("Range", fmt.Sprintf("0-%d", len(data)-1))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		delete(repo.uploads, id)
+		r.completeUpload(w, repo, name, query.Get("digest"), data)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) completeUpload(
+	w http.ResponseWriter,
+	repo *repository,
+	name string,
+	dgst string,
+	data []byte,
+) {
+	expected, err := digest.Parse(dgst)
+	if err != nil || expected != digest.FromBytes(data) {
+		writeError(w, http.StatusBadRequest, codeDigestInvalid, dgst)
+		return
+	}
+	repo.blobs[expected] = data
+	w.Header().Set("Docker-Content-Digest", dgst)
+	w.Header().Set("Location", "/v2/"+name+"/blobs/"+dgst)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (r *Registry) serveReferrers(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	subject, err := digest.Parse(ref)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeDigestInvalid, ref)
+		return
+	}
+	artifactType := req.URL.Query().Get("artifactType")
+	referrers := []ocispec.Descriptor{}
+	if repo, ok := r.repos[name]; ok {
+		for dgst, m := range repo.manifests {
+			parsed := parseManifest(m.content)
+			if parsed.Subject == nil || parsed.Subject.Digest != subject {
+				continue
+			}
+			desc := ocispec.Descriptor{
+				MediaType:    m.mediaType,
+				Digest:       dgst,
+				Size:         int64(len(m.content)),
+				ArtifactType: parsed.ArtifactType,
+				Annotations:  parsed.Annotations,
+			}
+			if desc.ArtifactType == "" && parsed.Config != nil {
+				desc.ArtifactType = parsed.Config.MediaType
+			}
+			if artifactType != "" && desc.ArtifactType != artifactType {
+				continue
+			}
+			referrers = append(referrers, desc)
+		}
+	}
+	sort.Slice(referrers, func(i, j int) bool {
+		return referrers[i].Digest < referrers[j].Digest
+	})
+	if artifactType != "" {
+		w.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+	writeJSON(w, ocispec.MediaTypeImageIndex, ocispec.Index{
+		Versioned: specsVersioned,
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: referrers,
+	})
+}
+
+// repo returns the repository of name, creating one if not exists.
+// The caller must hold the lock.
+func (r *Registry) repo(name string) *repository {
+	repo, ok := r.repos[name]
+	if !ok {
+		repo = newRepository()
+		r.repos[name] = repo
+	}
+	return repo
+}
+
+// resolve resolves a tag or digest to the digest of a manifest.
+func (repo *repository) resolve(ref string) (digest.Digest, bool) {
+	if dgst, err := digest.Parse(ref); err == nil {
+		_, ok := repo.manifests[dgst]
+		return dgst, ok
+	}
+	dgst, ok := repo.tags[ref]
+	return dgst, ok
+}
+
+// manifestFields contains the fields of manifests and indexes used by the
+// registry.
+type manifestFields struct {
+	ArtifactType string              `json:"artifactType,omitempty"`
+	Config       *ocispec.Descriptor `json:"config,omitempty"`
+	Subject      *ocispec.Descriptor `json:"subject,omitempty"`
+	Annotations  map[string]string   `json:"annotations,omitempty"`
+}
+
+func parseManifest(content []byte) manifestFields {
+	var m manifestFields
+	_ = json.Unmarshal(content, &m)
+	return m
+}
+
+// paginate returns the sorted page of items after the `last` query parameter
+// and the last item of the page if there are more items. Pages are of the
+// `n` query parameter in size, or of pageSize if not requested.
+func paginate(items []string, query url.Values, pageSize int) ([]string, string) {
+	sort.Strings(items)
+	if last := query.Get("last"); last != "" {
+		i := sort.SearchStrings(items, last)
+		if i < len(items) && items[i] == last {
+			i++
+		}
+		items = items[i:]
+	}
+	n, err := strconv.Atoi(query.Get("n"))
+	if err != nil || n <= 0 {
+		n = pageSize
+	}
+	if n <= 0 || n >= len(items) {
+		return items, ""
+	}
+	return items[:n], items[n-1]
+}
+
+func setLinkHeader(w http.ResponseWriter, path string, query url.Values, last string) {
+	next := url.Values{}
+	if n := query.Get("n"); n != "" {
+		next.Set("n", n)
+	}
+	next.Set("last", last)
+	w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=\"next\"", path, next.Encode()))
+}
+
+func writeJSON(w http.ResponseWriter, mediaType string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeUnsupported, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{
+			{"code": code, "message": message},
+		},
+	})
+}
+
+func cut(path, sep string) (string, string) {
+	i := strings.LastIndex(path, sep)
+	return path[:i], path[i+len(sep):]
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
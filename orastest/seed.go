@@ -0,0 +1,175 @@
+package orastest
+
+import (
+	"encoding/json"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var specsVersioned = specs.Versioned{SchemaVersion: 2}
+
+// File is a named layer of an artifact.
+type File struct {
+	Name      string
+	MediaType string
+	Content   []byte
+}
+
+// Artifact describes an artifact to be seeded into the registry.
+type Artifact struct {
+	// ArtifactType is the artifact type of the manifest.
+	ArtifactType string
+	// Files are pushed as layers annotated with their names.
+	Files []File
+	// Config is the config blob. An empty JSON config is used if not set.
+	Config []byte
+	// ConfigMediaType is the media type of Config.
+	ConfigMediaType string
+	// Subject is the subject of the manifest.
+	Subject *ocispec.Descriptor
+	// Annotations are the annotations of the manifest.
+	Annotations map[string]string
+}
+
+// PushBlob seeds a blob into the repository.
+func (r *Registry) PushBlob(repo, mediaType string, content []byte) ocispec.Descriptor {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	desc := descriptor(mediaType, content)
+	r.repo(repo).blobs[desc.Digest] = content
+	return desc
+}
+
+// PushManifest seeds a manifest into the repository and tags it if ref is a
+// tag.
+func (r *Registry) PushManifest(repo, ref, mediaType string, content []byte) ocispec.Descriptor {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.putManifestContent(repo, ref, mediaType, content)
+	desc := descriptor(mediaType, content)
+	if subject := parseManifest(content).Subject; subject != nil && r.noReferrers {
+		r.indexReferrer(repo, *subject, desc, content)
+	}
+	return desc
+}
+
+// PushArtifact seeds an artifact into the repository and tags it if ref is a
+// tag.
+func (r *Registry) PushArtifact(repo, ref string, artifact Artifact) ocispec.Descriptor {
+	config := descriptor(ocispec.MediaTypeEmptyJSON, []byte("{}"))
+	config.Data = []byte("{}")
+	if artifact.Config != nil {
+		config = r.PushBlob(repo, artifact.ConfigMediaType, artifact.Config)
+	} else {
+		r.PushBlob(repo, config.MediaType, config.Data)
+	}
+	manifest := ocispec.Manifest{
+		Versioned:    specsVersioned,
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifact.ArtifactType,
+		Config:       config,
+		Layers:       []ocispec.Descriptor{},
+		Subject:      artifact.Subject,
+		Annotations:  artifact.Annotations,
+	}
+	for _, f := range artifact.Files {
+		layer := r.PushBlob(repo, f.MediaType, f.Content)
+		layer.Annotations = map[string]string{ocispec.AnnotationTitle: f.Name}
+		manifest.Layers = append(manifest.Layers, layer)
+	}
+	content, _ := json.Marshal(manifest)
+	return r.PushManifest(repo, ref, ocispec.MediaTypeImageManifest, content)
+}
+
+// PushIndex seeds an index of the manifests into the repository and tags it
+// if ref is a tag.
+func (r *Registry) PushIndex(repo, ref string, manifests ...ocispec.Descriptor) ocispec.Descriptor {
+	index := ocispec.Index{
+		Versioned: specsVersioned,
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: manifests,
+	}
+	content, _ := json.Marshal(index)
+	return r.PushManifest(repo, ref, ocispec.MediaTypeImageIndex, content)
+}
+
+// Tag tags the manifest identified by ref in the repository.
+func (r *Registry) Tag(repo, ref, tag string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rp, ok := r.repos[repo]
+	if !ok {
+		return false
+	}
+	dgst, ok := rp.resolve(ref)
+	if ok {
+		rp.tags[tag] = dgst
+	}
+	return ok
+}
+
+// Manifest returns the content of the manifest identified by ref.
+func (r *Registry) Manifest(repo, ref string) ([]byte, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rp, ok := r.repos[repo]
+	if !ok {
+		return nil, false
+	}
+	dgst, ok := rp.resolve(ref)
+	if !ok {
+		return nil, false
+	}
+	return rp.manifests[dgst].content, true
+}
+
+// Blob returns the content of the blob.
+func (r *Registry) Blob(repo string, dgst digest.Digest) ([]byte, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rp, ok := r.repos[repo]
+	if !ok {
+		return nil, false
+	}
+	blob, ok := rp.blobs[dgst]
+	return blob, ok
+}
+
+// indexReferrer adds the referrer to the index tagged by the referrers tag
+// schema. The caller must hold the lock.
+func (r *Registry) indexReferrer(
+	repo string,
+	subject ocispec.Descriptor,
+	referrer ocispec.Descriptor,
+	content []byte,
+) {
+	parsed := parseManifest(content)
+	referrer.ArtifactType = parsed.ArtifactType
+	if referrer.ArtifactType == "" && parsed.Config != nil {
+		referrer.ArtifactType = parsed.Config.MediaType
+	}
+	referrer.Annotations = parsed.Annotations
+
+	tag := subject.Digest.Algorithm().String() + "-" + subject.Digest.Encoded()
+	index := ocispec.Index{
+		Versioned: specsVersioned,
+		MediaType: ocispec.MediaTypeImageIndex,
+	}
+	rp := r.repo(repo)
+	if dgst, ok := rp.tags[tag]; ok {
+		_ = json.Unmarshal(rp.manifests[dgst].content, &index)
+	}
+	index.Manifests = append(index.Manifests, referrer)
+	indexContent, _ := json.Marshal(index)
+	r.putManifestContent(repo, tag, ocispec.MediaTypeImageIndex, indexContent)
+}
+
+func descriptor(mediaType string, content []byte) ocispec.Descriptor {
+	return ocispec.Descriptor{
+		MediaType: mediaType,
+		Digest:    digest.FromBytes(content),
+		Size:      int64(len(content)),
+	}
+}
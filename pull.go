@@ -4,14 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
 	"sync"
 
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/file"
 
 	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/fileref"
+	"github.com/koolay/oras-sdk/graph"
 	"github.com/koolay/oras-sdk/option"
 )
 
@@ -21,50 +27,308 @@ type PullOptions struct {
 	option.Platform
 	option.Target
 
-	concurrency       int
-	KeepOldFiles      bool
-	IncludeSubject    bool
-	PathTraversal     bool
-	Output            string
+	concurrency  int
+	KeepOldFiles bool
+	// IncludeSubject pulls the subject of the artifact, if any, and the
+	// referrers of the artifact recursively. The files of each referrer are
+	// written to a subdirectory of Output, or of the directory of the
+	// referred artifact, named after its short digest.
+	IncludeSubject bool
+	// ReferrerArtifactType limits the referrers to pull to the artifact type
+	// if set.
+	ReferrerArtifactType string
+	PathTraversal        bool
+	Output               string
+	// ManifestConfigRef saves the manifest config blob to a path, in the form
+	// of `path[:mediaType]`. The config media type is validated if specified.
 	ManifestConfigRef string
+	// Include selects the layers to pull. All layers are selected if empty.
+	Include PullFilter
+	// Exclude skips the layers matching any of its criteria.
+	Exclude PullFilter
 }
 
-func RunPull(ctx context.Context, opts PullOptions) error {
+// PullResult describes the outcome of a pull.
+type PullResult struct {
+	// Root is the descriptor of the pulled root node.
+	Root ocispec.Descriptor
+	// Reference is the annotated reference of the pulled target.
+	Reference string
+	// Tag is the tag selected by the version constraint of the target, if
+	// any.
+	Tag string
+	// Files lists the named contents written to the output directory.
+	Files []PulledFile
+	// Empty indicates that no named content is pulled.
+	Empty bool
+	// Filtered lists the layers skipped by the include and exclude filters.
+	Filtered []ocispec.Descriptor
+	// Config is the manifest config file saved as ManifestConfigRef.
+	Config *PulledFile
+	// ArtifactType is the artifact type of a pulled referrer.
+	ArtifactType string
+	// Referrers lists the results of the pulled referrers.
+	Referrers []*PullResult
+}
+
+// PulledFile describes a named content written by a pull.
+type PulledFile struct {
+	// Name is the title annotation of the content.
+	Name      string
+	Path      string
+	Digest    digest.Digest
+	Size      int64
+	MediaType string
+	// Restored indicates that the file is restored from a deduplicated blob
+	// instead of being downloaded.
+	Restored bool
+}
+
+// Print prints the status of each file and the summary of the pull result to
+// out. It is independent of the event handler reporting the transfers while
+// pulling.
+func (r *PullResult) Print(out io.Writer) error {
+	for _, f := range r.Files {
+		status := "Downloaded "
+		if f.Restored {
+			status = "Restored   "
+		}
+		if _, err := fmt.Fprintln(out, status, display.ShortDigest(f.Descriptor()), f.Name); err != nil {
+			return err
+		}
+	}
+	if r.Empty {
+		if _, err := fmt.Fprintln(out, "Downloaded empty artifact"); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(out, "Pulled", r.Reference); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(out, "Digest:", r.Root.Digest); err != nil {
+		return err
+	}
+	for _, referrer := range r.Referrers {
+		if err := referrer.Print(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descriptor returns the descriptor of the pulled file.
+func (f PulledFile) Descriptor() ocispec.Descriptor {
+	return ocispec.Descriptor{
+		MediaType: f.MediaType,
+		Digest:    f.Digest,
+		Size:      f.Size,
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: f.Name,
+		},
+	}
+}
+
+func RunPull(ctx context.Context, opts PullOptions) (*PullResult, error) {
 	ctx, logger := opts.WithContext(ctx)
-	// Copy Options
-	var printed sync.Map
-	copyOptions := oras.DefaultCopyOptions
-	copyOptions.Concurrency = opts.concurrency
-	var err error
-	if opts.Platform.Platform != nil {
-		copyOptions.WithTargetPlatform(opts.Platform.Platform)
+	ctx = display.WithOperation(ctx, display.OperationPull)
+	handler, done := opts.Events()
+	defer done()
+	if err := opts.Include.Validate(); err != nil {
+		return nil, err
+	}
+	if err := opts.Exclude.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Offline && opts.VersionConstraint != "" {
+		// tags are listed from the origin only
+		return nil, errors.New("version constraint cannot be resolved offline")
 	}
 
 	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := opts.ResolveVersion(ctx, target); err != nil {
+		return nil, err
 	}
 	if err := opts.EnsureReferenceNotEmpty(); err != nil {
-		return err
+		return nil, err
 	}
-	src, err := opts.CachedTarget(target)
+	cached, err := opts.CachedTarget(target)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	dst, err := file.New(opts.Output)
+	src := display.NewTrackedTarget(cached, handler)
+
+	result, err := pullArtifact(
+		ctx, src, opts.Reference, opts.Output, opts.Platform.Platform, opts.ManifestConfigRef, opts.IncludeSubject,
+		opts, handler,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.Reference = opts.AnnotatedReference()
+	if opts.VersionConstraint != "" {
+		result.Tag = opts.Reference
+	}
+	if opts.IncludeSubject {
+		// list referrers through the cache so that they are available offline
+		finder, ok := cached.(content.ReadOnlyGraphStorage)
+		if !ok {
+			finder = target
+		}
+		visited := map[digest.Digest]bool{result.Root.Digest: true}
+		if err := pullReferrers(ctx, finder, src, result, opts.Output, visited, opts, handler); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// pullReferrers pulls the referrers of parent recursively. The files of each
+// referrer are written to a subdirectory of dir named after its short digest.
+func pullReferrers(
+	ctx context.Context,
+	finder content.ReadOnlyGraphStorage,
+	src oras.ReadOnlyTarget,
+	parent *PullResult,
+	dir string,
+	visited map[digest.Digest]bool,
+	opts PullOptions,
+	handler display.EventHandler,
+) error {
+	referrers, err := graph.Referrers(ctx, finder, parent.Root, opts.ReferrerArtifactType)
 	if err != nil {
 		return err
 	}
+	for _, referrer := range referrers {
+		if visited[referrer.Digest] {
+			continue
+		}
+		visited[referrer.Digest] = true
+		output := filepath.Join(dir, display.ShortDigest(referrer))
+		result, err := pullArtifact(ctx, src, referrer.Digest.String(), output, nil, "", false, opts, handler)
+		if err != nil {
+			return err
+		}
+		result.Reference = opts.DisplayReference(referrer.Digest)
+		result.ArtifactType = referrer.ArtifactType
+		if err := pullReferrers(ctx, finder, src, result, output, visited, opts, handler); err != nil {
+			return err
+		}
+		parent.Referrers = append(parent.Referrers, result)
+	}
+	return nil
+}
+
+// pullArtifact pulls the files of the artifact identified by reference from src
+// to the output directory. The manifest config is saved if configRef, in the
+// form of `path[:mediaType]`, is specified. The subject of the artifact is
+// pulled as well if includeSubject is set.
+func pullArtifact(
+	ctx context.Context,
+	src oras.ReadOnlyTarget,
+	reference string,
+	output string,
+	platform *ocispec.Platform,
+	configRef string,
+	includeSubject bool,
+	opts PullOptions,
+	handler display.EventHandler,
+) (*PullResult, error) {
+	var configPath, configMediaType string
+	if configRef != "" {
+		var err error
+		configPath, configMediaType, err = fileref.Parse(configRef, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy Options
+	var recorded sync.Map
+	copyOptions := oras.DefaultCopyOptions
+	copyOptions.Concurrency = opts.concurrency
+	if platform != nil {
+		copyOptions.WithTargetPlatform(platform)
+	}
+
+	dst, err := file.New(output)
+	if err != nil {
+		return nil, err
+	}
 	defer dst.Close()
 	dst.AllowPathTraversalOnWrite = opts.PathTraversal
 	dst.DisableOverwrite = opts.KeepOldFiles
 
-	pulledEmpty := true
+	var filesLock sync.Mutex
+	result := &PullResult{
+		Empty: true,
+	}
+	addFile := func(desc ocispec.Descriptor, restored bool) {
+		name := desc.Annotations[ocispec.AnnotationTitle]
+		filesLock.Lock()
+		defer filesLock.Unlock()
+		result.Files = append(result.Files, PulledFile{
+			Name:      name,
+			Path:      filepath.Join(output, name),
+			Digest:    desc.Digest,
+			Size:      desc.Size,
+			MediaType: desc.MediaType,
+			Restored:  restored,
+		})
+		result.Empty = false
+	}
+	var filtered sync.Map
+	copyOptions.FindSuccessors = func(
+		ctx context.Context,
+		fetcher content.Fetcher,
+		desc ocispec.Descriptor,
+	) ([]ocispec.Descriptor, error) {
+		nodes, subject, config, err := graph.Successors(ctx, fetcher, desc)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			// not a manifest
+			return nodes, nil
+		}
+		var successors []ocispec.Descriptor
+		if subject != nil && includeSubject {
+			successors = append(successors, *subject)
+		}
+		if configPath != "" {
+			if configMediaType != "" && config.MediaType != configMediaType {
+				return nil, fmt.Errorf(
+					"config media type mismatch: expect %q, got %q",
+					configMediaType,
+					config.MediaType,
+				)
+			}
+			if config.Annotations == nil {
+				config.Annotations = make(map[string]string)
+			}
+			config.Annotations[ocispec.AnnotationTitle] = configPath
+		}
+		successors = append(successors, *config)
+		for _, node := range nodes {
+			if !selectLayer(opts.Include, opts.Exclude, node) {
+				if _, loaded := filtered.LoadOrStore(generateContentKey(node), true); !loaded {
+					filesLock.Lock()
+					result.Filtered = append(result.Filtered, node)
+					filesLock.Unlock()
+				}
+				continue
+			}
+			successors = append(successors, node)
+		}
+		return successors, nil
+	}
 	copyOptions.PreCopy = func(ctx context.Context, desc ocispec.Descriptor) error {
-		if _, ok := printed.LoadOrStore(generateContentKey(desc), true); ok {
+		if _, ok := recorded.LoadOrStore(generateContentKey(desc), true); ok {
 			return nil
 		}
-		return display.PrintStatus(desc, "Downloading", opts.Verbose)
+		return handler.OnStart(ctx, desc)
 	}
 	copyOptions.PostCopy = func(ctx context.Context, desc ocispec.Descriptor) error {
 		// restore named but deduplicated successor nodes
@@ -73,27 +337,27 @@ func RunPull(ctx context.Context, opts PullOptions) error {
 			return err
 		}
 		for _, s := range successors {
+			if _, ok := filtered.Load(generateContentKey(s)); ok {
+				continue
+			}
 			if _, ok := s.Annotations[ocispec.AnnotationTitle]; ok {
-				if err := printOnce(&printed, s, "Restored   ", opts.Verbose); err != nil {
-					return err
+				if _, loaded := recorded.LoadOrStore(generateContentKey(s), true); !loaded {
+					addFile(s, true)
+					if err := handler.OnRestored(ctx, s); err != nil {
+						return err
+					}
 				}
 			}
 		}
-		name, ok := desc.Annotations[ocispec.AnnotationTitle]
-		if !ok {
-			if !opts.Verbose {
-				return nil
-			}
-			name = desc.MediaType
-		} else {
+		if _, ok := desc.Annotations[ocispec.AnnotationTitle]; ok {
 			// named content downloaded
-			pulledEmpty = false
+			addFile(desc, false)
 		}
-		printed.Store(generateContentKey(desc), true)
-		return display.Print("Downloaded ", display.ShortDigest(desc), name)
+		recorded.Store(generateContentKey(desc), true)
+		return handler.OnComplete(ctx, desc)
 	}
 
-	desc, err := oras.Copy(ctx, src, opts.Reference, dst, opts.Reference, copyOptions)
+	desc, err := oras.Copy(ctx, src, reference, dst, reference, copyOptions)
 	if err != nil {
 		if errors.Is(err, file.ErrPathTraversalDisallowed) {
 			err = fmt.Errorf(
@@ -102,14 +366,18 @@ func RunPull(ctx context.Context, opts PullOptions) error {
 				err,
 			)
 		}
-		return err
+		return nil, err
 	}
-	if pulledEmpty {
-		fmt.Println("Downloaded empty artifact")
+	result.Root = desc
+	sort.Slice(result.Files, func(i, j int) bool {
+		return result.Files[i].Path < result.Files[j].Path
+	})
+	for i, f := range result.Files {
+		if configPath != "" && f.Name == configPath {
+			result.Config = &result.Files[i]
+		}
 	}
-	fmt.Println("Pulled", opts.AnnotatedReference())
-	fmt.Println("Digest:", desc.Digest)
-	return nil
+	return result, nil
 }
 
 // generateContentKey generates a unique key for each content descriptor, using
@@ -117,10 +385,3 @@ func RunPull(ctx context.Context, opts PullOptions) error {
 func generateContentKey(desc ocispec.Descriptor) string {
 	return desc.Digest.String() + desc.Annotations[ocispec.AnnotationTitle]
 }
-
-func printOnce(printed *sync.Map, s ocispec.Descriptor, msg string, verbose bool) error {
-	if _, loaded := printed.LoadOrStore(generateContentKey(s), true); loaded {
-		return nil
-	}
-	return display.PrintStatus(s, msg, verbose)
-}
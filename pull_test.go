@@ -1,34 +1,221 @@
 package artifacts
 
 import (
+	"bytes"
 	"context"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/exp/slog"
+	"github.com/stretchr/testify/require"
 
+	"github.com/koolay/oras-sdk/display"
 	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
 )
 
+// newPullOptions returns pull options for the reference in the registry.
+func newPullOptions(reg *orastest.Registry, repo, ref, output string) PullOptions {
+	opts := PullOptions{}
+	opts.Output = output
+	opts.RawReference = reg.Reference(repo, ref)
+	opts.Type = option.TargetTypeRemote
+	opts.Remote = option.NewRemote(true, "", "")
+	opts.EventHandler = display.NopHandler{}
+	return opts
+}
+
 func TestRunPull(t *testing.T) {
-	defaultLog := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level:     slog.LevelDebug,
-		AddSource: true,
-	}))
-	slog.SetDefault(defaultLog)
-	ctx := context.Background()
-	username := os.Getenv("YOUR_AK")
-	pssword := os.Getenv("YOUR_SK")
+	reg := orastest.NewRegistry(orastest.WithBasicAuth("user", "secret"))
+	defer reg.Close()
+	root := reg.PushArtifact("library/hello", "latest", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "hello.txt", MediaType: "text/plain", Content: []byte("hello")},
+			{Name: "world.txt", MediaType: "text/plain", Content: []byte("world")},
+		},
+	})
 
-	opts := PullOptions{}
-	opts.Output = "/tmp/oras-temp"
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := newPullOptions(reg, "library/hello", "latest", dir)
 	opts.concurrency = 4
-	opts.RawReference = "docker.io/library/busybox:latest"
-	opts.Type = option.TargetTypeRemote
-	opts.Debug = true
 	opts.Verbose = true
-	opts.Remote = option.NewRemote(false, username, pssword)
-	err := RunPull(ctx, opts)
+	opts.Remote = option.NewRemote(true, "user", "secret")
+	result, err := RunPull(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, root.Digest, result.Root.Digest)
+	assert.False(t, result.Empty)
+	require.Len(t, result.Files, 2)
+	assert.Equal(t, filepath.Join(dir, "hello.txt"), result.Files[0].Path)
+	got, err := os.ReadFile(filepath.Join(dir, "world.txt"))
 	assert.Nil(t, err)
+	assert.Equal(t, "world", string(got))
+
+	// wrong credential
+	opts.Remote = option.NewRemote(true, "user", "wrong")
+	_, err = RunPull(ctx, opts)
+	assert.NotNil(t, err)
+}
+
+func TestRunPull_failure(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "hello.txt", MediaType: "text/plain", Content: []byte("hello")},
+		},
+	})
+	reg.InjectFailure(orastest.Failure{
+		Method: http.MethodGet,
+		Path:   "/blobs/",
+		Status: http.StatusForbidden,
+		Times:  1,
+	})
+
+	opts := newPullOptions(reg, "hello", "v1", t.TempDir())
+	_, err := RunPull(context.Background(), opts)
+	assert.NotNil(t, err)
+
+	// the failure is injected only once
+	_, err = RunPull(context.Background(), opts)
+	assert.Nil(t, err)
+}
+
+func TestRunPull_filter(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("bundle", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("a")},
+			{Name: "b.json", MediaType: "application/json", Content: []byte("{}")},
+			{Name: "c.txt", MediaType: "text/plain", Content: []byte("c")},
+		},
+	})
+
+	dir := t.TempDir()
+	opts := newPullOptions(reg, "bundle", "v1", dir)
+	opts.Include.Titles = []string{"*.txt"}
+	opts.Exclude.Titles = []string{"c.*"}
+	result, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	assert.Equal(t, "a.txt", result.Files[0].Name)
+	assert.Len(t, result.Filtered, 2)
+	_, err = os.Stat(filepath.Join(dir, "b.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunPull_includeSubject(t *testing.T) {
+	for name, regOpts := range map[string][]orastest.Option{
+		"referrers API":        nil,
+		"referrers tag schema": {orastest.WithoutReferrersAPI()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reg := orastest.NewRegistry(regOpts...)
+			defer reg.Close()
+			root := reg.PushArtifact("app", "v1", orastest.Artifact{
+				ArtifactType: "application/vnd.test.artifact",
+				Files: []orastest.File{
+					{Name: "app.txt", MediaType: "text/plain", Content: []byte("app")},
+				},
+			})
+			sig := reg.PushArtifact("app", "", orastest.Artifact{
+				ArtifactType: "application/vnd.test.signature",
+				Subject:      &root,
+				Files: []orastest.File{
+					{Name: "sig.txt", MediaType: "text/plain", Content: []byte("sig")},
+				},
+			})
+			reg.PushArtifact("app", "", orastest.Artifact{
+				ArtifactType: "application/vnd.test.sbom",
+				Subject:      &root,
+				Files: []orastest.File{
+					{Name: "sbom.txt", MediaType: "text/plain", Content: []byte("sbom")},
+				},
+			})
+
+			dir := t.TempDir()
+			opts := newPullOptions(reg, "app", "v1", dir)
+			opts.IncludeSubject = true
+			opts.ReferrerArtifactType = "application/vnd.test.signature"
+			result, err := RunPull(context.Background(), opts)
+			require.NoError(t, err)
+			require.Len(t, result.Referrers, 1)
+			assert.Equal(t, sig.Digest, result.Referrers[0].Root.Digest)
+			got, err := os.ReadFile(filepath.Join(dir, display.ShortDigest(sig), "sig.txt"))
+			assert.Nil(t, err)
+			assert.Equal(t, "sig", string(got))
+
+			// the subject is not pulled into the referrer directory
+			_, err = os.Stat(filepath.Join(dir, display.ShortDigest(sig), "app.txt"))
+			assert.True(t, os.IsNotExist(err))
+			require.Len(t, result.Referrers[0].Files, 1)
+			assert.Equal(t, "sig.txt", result.Referrers[0].Files[0].Name)
+		})
+	}
+}
+
+func TestRunPull_manifestConfigRef(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	reg.PushArtifact("chart", "v1", orastest.Artifact{
+		Config:          []byte(`{"name":"chart"}`),
+		ConfigMediaType: "application/vnd.test.config.v1+json",
+		Files: []orastest.File{
+			{Name: "chart.tgz", MediaType: "application/vnd.test.chart", Content: []byte("chart")},
+		},
+	})
+
+	dir := t.TempDir()
+	opts := newPullOptions(reg, "chart", "v1", dir)
+	opts.ManifestConfigRef = "meta/config.json:application/vnd.test.config.v1+json"
+	result, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	require.NotNil(t, result.Config)
+	got, err := os.ReadFile(filepath.Join(dir, "meta", "config.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"chart"}`, string(got))
+
+	opts.Output = t.TempDir()
+	opts.ManifestConfigRef = "config.json:application/json"
+	_, err = RunPull(context.Background(), opts)
+	assert.NotNil(t, err)
+}
+
+func TestPullResult_descriptor(t *testing.T) {
+	f := PulledFile{Name: "a.txt", MediaType: "text/plain"}
+	assert.Equal(t, "a.txt", f.Descriptor().Annotations[ocispec.AnnotationTitle])
+}
+
+func TestPullResult_print(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{
+		Files: []orastest.File{
+			{Name: "a.txt", MediaType: "text/plain", Content: []byte("same")},
+			{Name: "b.txt", MediaType: "text/plain", Content: []byte("same")},
+		},
+	})
+	result, err := RunPull(context.Background(), newPullOptions(reg, "app", "v1", t.TempDir()))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, result.Print(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	// one of the files is restored from the other as they share the blob
+	files := strings.Join(lines[:2], "\n")
+	assert.Contains(t, files, "Downloaded  ")
+	assert.Contains(t, files, "Restored    ")
+	assert.Contains(t, files, "a.txt")
+	assert.Contains(t, files, "b.txt")
+	assert.Equal(t, "Pulled "+result.Reference, lines[2])
+	assert.Equal(t, "Digest: "+root.Digest.String(), lines[3])
 }
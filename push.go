@@ -0,0 +1,265 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/errdef"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/fileref"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type PushOptions struct {
+	option.Common
+	option.Platform
+	option.Target
+
+	concurrency int
+	// FileRefs lists the files to push in the form of `path[:mediaType]`.
+	FileRefs []string
+	// ExtraTags are tagged to the pushed manifest in addition to the tag in
+	// the target reference.
+	ExtraTags         []string
+	ArtifactType      string
+	ManifestConfigRef string
+	// ManifestAnnotations are added to the packed manifest.
+	ManifestAnnotations map[string]string
+	// ConfigAnnotations are added to the config descriptor of
+	// ManifestConfigRef.
+	ConfigAnnotations map[string]string
+}
+
+func RunPush(ctx context.Context, opts PushOptions) error {
+	ctx, logger := opts.WithContext(ctx)
+	ctx = display.WithOperation(ctx, display.OperationPush)
+	handler, done := opts.Events()
+	defer done()
+	if opts.ManifestConfigRef != "" && opts.Platform.Platform != nil {
+		return errors.New("manifest config and platform cannot be specified together")
+	}
+
+	// prepare pack
+	packOpts := oras.PackManifestOptions{
+		ManifestAnnotations: opts.ManifestAnnotations,
+		ConfigAnnotations:   opts.ConfigAnnotations,
+	}
+	store, err := file.New("")
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	memoryStore := memory.New()
+	switch {
+	case opts.ManifestConfigRef != "":
+		path, cfgMediaType, err := fileref.Parse(opts.ManifestConfigRef, oras.MediaTypeUnknownConfig)
+		if err != nil {
+			return err
+		}
+		desc, err := addFile(ctx, store, "$config", cfgMediaType, path)
+		if err != nil {
+			return err
+		}
+		// the config is not a file to be pulled, drop its title annotation
+		desc.Annotations = packOpts.ConfigAnnotations
+		packOpts.ConfigDescriptor = &desc
+	case opts.Platform.Platform != nil:
+		// record the platform in an image config so that the artifact can
+		// be selected by platform on pulling
+		desc, err := pushPlatformConfig(ctx, memoryStore, opts.Platform.Platform)
+		if err != nil {
+			return err
+		}
+		packOpts.ConfigDescriptor = &desc
+	}
+	descs, err := loadFiles(ctx, store, opts.FileRefs, opts.Verbose, handler)
+	if err != nil {
+		return err
+	}
+	packOpts.Layers = descs
+	artifactType := opts.ArtifactType
+	if artifactType == "" && packOpts.ConfigDescriptor == nil {
+		artifactType = oras.MediaTypeUnknownArtifact
+	}
+	root, err := oras.PackManifest(ctx, memoryStore, oras.PackManifestVersion1_1_RC4, artifactType, packOpts)
+	if err != nil {
+		return err
+	}
+
+	// prepare push
+	dst, err := opts.NewTarget(opts.Common, logger)
+	if err != nil {
+		return err
+	}
+	copyOptions := oras.DefaultCopyOptions
+	copyOptions.Concurrency = opts.concurrency
+	union := multiReadOnlyTarget{memoryStore, store}
+	updateEventOption(&copyOptions.CopyGraphOptions, union, handler)
+	src := display.NewTrackedTarget(union, handler)
+
+	// push
+	if tag := opts.Reference; tag == "" {
+		err = oras.CopyGraph(ctx, src, dst, root, copyOptions.CopyGraphOptions)
+	} else {
+		if err = memoryStore.Tag(ctx, root, root.Digest.String()); err != nil {
+			return err
+		}
+		_, err = oras.Copy(ctx, src, root.Digest.String(), dst, tag, copyOptions)
+	}
+	if err != nil {
+		return err
+	}
+	if err := handler.OnStatus(ctx, "Pushed", opts.AnnotatedReference()); err != nil {
+		return err
+	}
+
+	if len(opts.ExtraTags) != 0 {
+		contentBytes, err := content.FetchAll(ctx, memoryStore, root)
+		if err != nil {
+			return err
+		}
+		tagBytesNOpts := oras.DefaultTagBytesNOptions
+		tagBytesNOpts.Concurrency = opts.concurrency
+		tagger := display.NewTagStatusHintPrinter(dst, opts.Path, handler)
+		if _, err = oras.TagBytesN(ctx, tagger, root.MediaType, contentBytes, opts.ExtraTags, tagBytesNOpts); err != nil {
+			return err
+		}
+	}
+	return handler.OnStatus(ctx, "Digest:", root.Digest.String())
+}
+
+// loadFiles adds the referenced files to the file store and returns their
+// descriptors.
+func loadFiles(
+	ctx context.Context,
+	store *file.Store,
+	fileRefs []string,
+	verbose bool,
+	handler display.EventHandler,
+) ([]ocispec.Descriptor, error) {
+	var files []ocispec.Descriptor
+	for _, fileRef := range fileRefs {
+		filename, mediaType, err := fileref.Parse(fileRef, "")
+		if err != nil {
+			return nil, err
+		}
+
+		// get shortest absolute path as unique name
+		name := filepath.Clean(filename)
+		if !filepath.IsAbs(name) {
+			name = filepath.ToSlash(name)
+		}
+
+		if verbose {
+			if err := handler.OnStatus(ctx, "Preparing", name); err != nil {
+				return nil, err
+			}
+		}
+		desc, err := addFile(ctx, store, name, mediaType, filename)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, desc)
+	}
+	if len(files) == 0 {
+		if err := handler.OnStatus(ctx, "Uploading empty artifact", ""); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func addFile(
+	ctx context.Context,
+	store *file.Store,
+	name string,
+	mediaType string,
+	path string,
+) (ocispec.Descriptor, error) {
+	desc, err := store.Add(ctx, name, mediaType, path)
+	if err != nil {
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr
+		}
+		return ocispec.Descriptor{}, err
+	}
+	return desc, nil
+}
+
+// pushPlatformConfig pushes an image config blob describing the platform.
+func pushPlatformConfig(
+	ctx context.Context,
+	pusher content.Pusher,
+	platform *ocispec.Platform,
+) (ocispec.Descriptor, error) {
+	config := ocispec.Image{Platform: *platform}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return oras.PushBytes(ctx, pusher, ocispec.MediaTypeImageConfig, configBytes)
+}
+
+// multiReadOnlyTarget is a read-only target which fetches content from the
+// first target containing it.
+type multiReadOnlyTarget []oras.ReadOnlyTarget
+
+// Fetch fetches the content from the first target containing it.
+func (m multiReadOnlyTarget) Fetch(
+	ctx context.Context,
+	target ocispec.Descriptor,
+) (rc io.ReadCloser, err error) {
+	lastErr := errdef.ErrNotFound
+	for _, c := range m {
+		rc, err = c.Fetch(ctx, target)
+		if err == nil {
+			return rc, nil
+		}
+		if !errors.Is(err, errdef.ErrNotFound) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// Exists returns true if the content exists in any of the targets.
+func (m multiReadOnlyTarget) Exists(ctx context.Context, target ocispec.Descriptor) (bool, error) {
+	for _, c := range m {
+		exists, err := c.Exists(ctx, target)
+		if err != nil {
+			return false, err
+		}
+		if exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Resolve resolves the reference from the first target containing it.
+func (m multiReadOnlyTarget) Resolve(ctx context.Context, ref string) (ocispec.Descriptor, error) {
+	lastErr := errdef.ErrNotFound
+	for _, c := range m {
+		desc, err := c.Resolve(ctx, ref)
+		if err == nil {
+			return desc, nil
+		}
+		if !errors.Is(err, errdef.ErrNotFound) {
+			return ocispec.Descriptor{}, err
+		}
+		lastErr = err
+	}
+	return ocispec.Descriptor{}, lastErr
+}
@@ -0,0 +1,146 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/option"
+)
+
+func TestRunPush(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { _ = os.Chdir(wd) }()
+	require.NoError(t, os.WriteFile("hello.txt", []byte("hello"), 0o600))
+
+	opts := PushOptions{}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = filepath.Join(dir, "layout") + ":v1"
+	opts.FileRefs = []string{"hello.txt:text/plain"}
+	opts.ExtraTags = []string{"latest"}
+	opts.ArtifactType = "application/vnd.test.artifact"
+	err = RunPush(ctx, opts)
+	assert.Nil(t, err)
+
+	pullOpts := PullOptions{}
+	pullOpts.Type = option.TargetTypeOCILayout
+	pullOpts.RawReference = filepath.Join(dir, "layout") + ":latest"
+	pullOpts.Output = filepath.Join(dir, "out")
+	result, err := RunPull(ctx, pullOpts)
+	assert.Nil(t, err)
+	assert.False(t, result.Empty)
+	assert.Len(t, result.Files, 1)
+	assert.Equal(t, filepath.Join(dir, "out", "hello.txt"), result.Files[0].Path)
+	got, err := os.ReadFile(filepath.Join(dir, "out", "hello.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(got))
+}
+
+func TestRunPush_manifestConfig(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	chdir(t, dir)
+	require.NoError(t, os.WriteFile("config.json", []byte("{}"), 0o600))
+	require.NoError(t, os.WriteFile("hello.txt", []byte("hello"), 0o600))
+
+	opts := PushOptions{}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = filepath.Join(dir, "layout") + ":v1"
+	opts.FileRefs = []string{"hello.txt:text/plain"}
+	opts.ManifestConfigRef = "config.json:application/vnd.test.config+json"
+	opts.ConfigAnnotations = map[string]string{"foo": "bar"}
+	require.NoError(t, RunPush(ctx, opts))
+
+	fetchOpts := FetchManifestOptions{}
+	fetchOpts.Type = option.TargetTypeOCILayout
+	fetchOpts.RawReference = filepath.Join(dir, "layout") + ":v1"
+	fetched, err := FetchManifest(ctx, fetchOpts)
+	require.NoError(t, err)
+	var manifest ocispec.Manifest
+	require.NoError(t, fetched.Decode(&manifest))
+	assert.Equal(t, "application/vnd.test.config+json", manifest.Config.MediaType)
+	assert.Equal(t, map[string]string{"foo": "bar"}, manifest.Config.Annotations)
+
+	pullOpts := PullOptions{}
+	pullOpts.Type = option.TargetTypeOCILayout
+	pullOpts.RawReference = filepath.Join(dir, "layout") + ":v1"
+	pullOpts.Output = filepath.Join(dir, "out")
+	result, err := RunPull(ctx, pullOpts)
+	require.NoError(t, err)
+	require.Len(t, result.Files, 1)
+	assert.Equal(t, filepath.Join(dir, "out", "hello.txt"), result.Files[0].Path)
+	_, err = os.Stat(filepath.Join(dir, "out", "$config"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the config cannot be recorded together with a platform
+	opts.Platform.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	assert.NotNil(t, RunPush(ctx, opts))
+}
+
+// chdir changes the working directory to dir until the test finishes.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestRunPush_events(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+	layout := filepath.Join(dir, "layout")
+
+	var buf bytes.Buffer
+	opts := PushOptions{}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = layout + ":v1"
+	opts.FileRefs = []string{path + ":text/plain"}
+	opts.ExtraTags = []string{"latest"}
+	opts.EventHandler = display.NewTextHandler(&buf, false)
+	stdout := captureStdout(t, func() {
+		require.NoError(t, RunPush(ctx, opts))
+	})
+	assert.Empty(t, stdout)
+	output := buf.String()
+	assert.Contains(t, output, "Uploaded  ")
+	assert.Contains(t, output, "Pushed [oci-layout] "+layout+":v1\n")
+	assert.Contains(t, output, "Tagged latest\n")
+	assert.Contains(t, output, "Digest: sha256:")
+
+	// nothing is printed without an event handler
+	opts.EventHandler = nil
+	stdout = captureStdout(t, func() {
+		require.NoError(t, RunPush(ctx, opts))
+	})
+	assert.Empty(t, stdout)
+}
+
+// captureStdout returns what fn writes to STDOUT.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	require.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(out)
+}
@@ -0,0 +1,37 @@
+package artifacts
+
+import (
+	"context"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+
+	"github.com/koolay/oras-sdk/option"
+)
+
+type ResolveOptions struct {
+	option.Common
+	option.Platform
+	option.Target
+}
+
+// Resolve returns the descriptor of the tag or digest referenced by opts
+// without downloading the content. Registries are requested with HEAD. If a
+// platform is specified, the manifest of the platform is resolved when the
+// reference points at an index.
+func Resolve(ctx context.Context, opts ResolveOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	target, err := opts.NewReadonlyTarget(ctx, opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.ResolveVersion(ctx, target); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.EnsureReferenceNotEmpty(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	resolveOpts := oras.DefaultResolveOptions
+	resolveOpts.TargetPlatform = opts.Platform.Platform
+	return oras.Resolve(ctx, target, opts.Reference, resolveOpts)
+}
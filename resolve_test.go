@@ -0,0 +1,83 @@
+package artifacts
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	amd64 := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.amd64"})
+	amd64.Platform = &ocispec.Platform{OS: "linux", Architecture: "amd64"}
+	arm64 := reg.PushArtifact("app", "", orastest.Artifact{ArtifactType: "application/vnd.test.arm64"})
+	arm64.Platform = &ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	index := reg.PushIndex("app", "v1", amd64, arm64)
+
+	opts := ResolveOptions{}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v1")
+	opts.Remote = option.NewRemote(true, "", "")
+	reg.ResetRequests()
+	desc, err := Resolve(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, index.Digest, desc.Digest)
+	assert.Equal(t, ocispec.MediaTypeImageIndex, desc.MediaType)
+	assert.Equal(t, index.Size, desc.Size)
+	assert.Contains(t, reg.Requests(), http.MethodHead+" /v2/app/manifests/v1")
+	assert.NotContains(t, reg.Requests(), http.MethodGet+" /v2/app/manifests/v1")
+
+	opts.SetPlatform("linux/arm64")
+	require.NoError(t, opts.Platform.Parse())
+	desc, err = Resolve(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, arm64.Digest, desc.Digest)
+
+	opts.SetPlatform("linux/s390x")
+	require.NoError(t, opts.Platform.Parse())
+	_, err = Resolve(ctx, opts)
+	assert.NotNil(t, err)
+
+	opts = ResolveOptions{}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", "v2")
+	opts.Remote = option.NewRemote(true, "", "")
+	_, err = Resolve(ctx, opts)
+	assert.NotNil(t, err)
+}
+
+func TestResolve_ociLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "layout")
+	store, err := oci.New(dir)
+	require.NoError(t, err)
+	root, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1_RC4,
+		"application/vnd.test.artifact", oras.PackManifestOptions{})
+	require.NoError(t, err)
+	require.NoError(t, store.Tag(ctx, root, "v1"))
+
+	opts := ResolveOptions{}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = dir + ":v1"
+	desc, err := Resolve(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, root.Digest, desc.Digest)
+	assert.Equal(t, ocispec.MediaTypeImageManifest, desc.MediaType)
+
+	opts.RawReference = dir + "@" + root.Digest.String()
+	desc, err = Resolve(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, root.Size, desc.Size)
+}
@@ -0,0 +1,58 @@
+package artifacts
+
+import (
+	"context"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+
+	"github.com/koolay/oras-sdk/display"
+)
+
+// updateEventOption sets the copy options to report transfer events of
+// uploading or copying to handler.
+func updateEventOption(
+	opts *oras.CopyGraphOptions,
+	fetcher content.Fetcher,
+	handler display.EventHandler,
+) {
+	committed := &sync.Map{}
+	opts.PreCopy = handler.OnStart
+	opts.OnCopySkipped = func(ctx context.Context, desc ocispec.Descriptor) error {
+		committed.Store(desc.Digest.String(), desc.Annotations[ocispec.AnnotationTitle])
+		return handler.OnSkipped(ctx, desc)
+	}
+	opts.PostCopy = func(ctx context.Context, desc ocispec.Descriptor) error {
+		committed.Store(desc.Digest.String(), desc.Annotations[ocispec.AnnotationTitle])
+		if err := notifySuccessorRestored(ctx, desc, fetcher, committed, handler); err != nil {
+			return err
+		}
+		return handler.OnComplete(ctx, desc)
+	}
+}
+
+// notifySuccessorRestored reports the deduplicated successors of desc, which
+// are committed under a different name, as restored.
+func notifySuccessorRestored(
+	ctx context.Context,
+	desc ocispec.Descriptor,
+	fetcher content.Fetcher,
+	committed *sync.Map,
+	handler display.EventHandler,
+) error {
+	successors, err := content.Successors(ctx, fetcher, desc)
+	if err != nil {
+		return err
+	}
+	for _, s := range successors {
+		name := s.Annotations[ocispec.AnnotationTitle]
+		if v, ok := committed.Load(s.Digest.String()); ok && v != name {
+			if err := handler.OnRestored(ctx, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/option"
+)
+
+type TagOptions struct {
+	option.Common
+	option.Target
+
+	concurrency int
+	// Tags are applied to the manifest referenced by the target.
+	Tags []string
+}
+
+// TagError reports the tags failed to be applied, keyed by tag.
+type TagError map[string]error
+
+func (e TagError) Error() string {
+	tags := make([]string, 0, len(e))
+	for tag := range e {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	msgs := make([]string, len(tags))
+	for i, tag := range tags {
+		msgs[i] = fmt.Sprintf("failed to tag %q: %v", tag, e[tag])
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e TagError) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// RunTag resolves the reference of opts and tags the manifest with opts.Tags
+// concurrently. All tags are attempted: if some of them fail, the descriptor
+// of the manifest is returned with a TagError reporting the failed tags.
+func RunTag(ctx context.Context, opts TagOptions) (ocispec.Descriptor, error) {
+	ctx, logger := opts.WithContext(ctx)
+	handler, done := opts.Events()
+	defer done()
+	if len(opts.Tags) == 0 {
+		return ocispec.Descriptor{}, errors.New("at least one tag required")
+	}
+	target, err := opts.NewExistingTarget(opts.Common, logger)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.ResolveTargetVersion(ctx, target); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := opts.EnsureReferenceNotEmpty(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc, err := oras.Resolve(ctx, target, opts.Reference, oras.DefaultResolveOptions)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	printer := display.NewTagStatusHintPrinter(target, fmt.Sprintf("[%s] %s", opts.Type, opts.Path), handler)
+	tag := func(ref string) error {
+		return printer.Tag(ctx, desc, ref)
+	}
+	if pusher, ok := printer.(registry.ReferencePusher); ok {
+		// fetch the manifest once instead of once per tag
+		manifest, err := content.FetchAll(ctx, target, desc)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		tag = func(ref string) error {
+			return pusher.PushReference(ctx, desc, bytes.NewReader(manifest), ref)
+		}
+	}
+
+	var lock sync.Mutex
+	tagErr := TagError{}
+	var eg errgroup.Group
+	eg.SetLimit(defaultConcurrency)
+	if opts.concurrency > 0 {
+		eg.SetLimit(opts.concurrency)
+	}
+	for _, ref := range opts.Tags {
+		ref := ref
+		eg.Go(func() error {
+			if err := tag(ref); err != nil {
+				lock.Lock()
+				tagErr[ref] = err
+				lock.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = eg.Wait()
+	if len(tagErr) != 0 {
+		return desc, tagErr
+	}
+	return desc, nil
+}
+
+func (opts *TagOptions) setConcurrency(n int) {
+	opts.concurrency = n
+}
@@ -0,0 +1,99 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/oci"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+func TestRunTag(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	root := reg.PushArtifact("app", "v1", orastest.Artifact{ArtifactType: "application/vnd.test.artifact"})
+	reg.InjectFailure(orastest.Failure{
+		Method: http.MethodPut,
+		Path:   "/manifests/broken",
+		Status: http.StatusForbidden,
+	})
+
+	opts := TagOptions{Tags: []string{"prod", "stable", "broken"}}
+	opts.Type = option.TargetTypeRemote
+	opts.RawReference = reg.Reference("app", root.Digest.String())
+	opts.Remote = option.NewRemote(true, "", "")
+	reg.ResetRequests()
+	desc, err := RunTag(ctx, opts)
+	assert.Equal(t, root.Digest, desc.Digest)
+	var tagErr TagError
+	require.True(t, errors.As(err, &tagErr), err)
+	assert.Len(t, tagErr, 1)
+	assert.Contains(t, tagErr, "broken")
+	for _, tag := range []string{"prod", "stable"} {
+		_, ok := reg.Manifest("app", tag)
+		assert.True(t, ok, tag)
+	}
+	_, ok := reg.Manifest("app", "broken")
+	assert.False(t, ok)
+	// the manifest is fetched once for all tags
+	var fetches int
+	for _, req := range reg.Requests() {
+		if req == http.MethodGet+" /v2/app/manifests/"+root.Digest.String() {
+			fetches++
+		}
+	}
+	assert.Equal(t, 1, fetches)
+
+	opts.Tags = nil
+	_, err = RunTag(ctx, opts)
+	assert.NotNil(t, err)
+
+	opts.Tags = []string{"latest"}
+	opts.RawReference = reg.Reference("app", "v2")
+	_, err = RunTag(ctx, opts)
+	assert.NotNil(t, err)
+	assert.False(t, errors.As(err, &tagErr))
+}
+
+func TestRunTag_ociLayout(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "layout")
+	store, err := oci.New(dir)
+	require.NoError(t, err)
+	root, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1_RC4,
+		"application/vnd.test.artifact", oras.PackManifestOptions{})
+	require.NoError(t, err)
+	require.NoError(t, store.Tag(ctx, root, "v1"))
+
+	opts := TagOptions{Tags: []string{"prod", "stable"}}
+	opts.Type = option.TargetTypeOCILayout
+	opts.RawReference = dir + ":v1"
+	opts.setConcurrency(1)
+	desc, err := RunTag(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, root.Digest, desc.Digest)
+
+	store, err = oci.New(dir)
+	require.NoError(t, err)
+	for _, tag := range []string{"prod", "stable"} {
+		got, err := store.Resolve(ctx, tag)
+		require.NoError(t, err)
+		assert.Equal(t, root.Digest, got.Digest)
+	}
+
+	// no layout is created for manifests failed to be tagged
+	missing := filepath.Join(t.TempDir(), "missing")
+	opts.RawReference = missing + ":v1"
+	_, err = RunTag(ctx, opts)
+	assert.NotNil(t, err)
+	assert.NoDirExists(t, missing)
+}
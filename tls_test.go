@@ -0,0 +1,232 @@
+package artifacts
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/koolay/oras-sdk/display"
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+)
+
+// testCert is a certificate with its private key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert issues a certificate valid until notAfter, signed by parent or
+// self-signed as a CA if parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert, notAfter time.Time) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+// writePEM writes the PEM blocks to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	var data []byte
+	for _, block := range blocks {
+		data = append(data, pem.EncodeToMemory(block)...)
+	}
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestRunPull_mutualTLS(t *testing.T) {
+	expiry := time.Now().Add(time.Hour)
+	ca := newTestCert(t, "test CA", nil, expiry)
+	client := newTestCert(t, "client", ca, expiry)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	reg := orastest.NewRegistry(orastest.WithTLS(clientCAs))
+	defer reg.Close()
+	reg.PushArtifact("hello", "v1", orastest.Artifact{
+		ArtifactType: "application/vnd.test.artifact",
+		Files: []orastest.File{
+			{Name: "hello.txt", MediaType: "text/plain", Content: []byte("hello")},
+		},
+	})
+
+	dir := t.TempDir()
+	caPath := writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: reg.Certificate().Raw})
+	certPath := writePEM(t, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: client.cert.Raw})
+	keyDER, err := x509.MarshalECPrivateKey(client.key)
+	require.NoError(t, err)
+	keyPath := writePEM(t, dir, "client.key", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	pfx, err := pkcs12.Modern.Encode(client.key, client.cert, []*x509.Certificate{ca.cert}, "secret")
+	require.NoError(t, err)
+	pfxPath := filepath.Join(dir, "client.p12")
+	require.NoError(t, os.WriteFile(pfxPath, pfx, 0o600))
+	expired := newTestCert(t, "expired", ca, time.Now().Add(-time.Minute))
+	expiredPath := writePEM(t, dir, "expired.pem", &pem.Block{Type: "CERTIFICATE", Bytes: expired.cert.Raw})
+	bundlePath := writePEM(t, dir, "bundle.pem",
+		&pem.Block{Type: "CERTIFICATE", Bytes: expired.cert.Raw},
+		&pem.Block{Type: "CERTIFICATE", Bytes: reg.Certificate().Raw},
+	)
+
+	pull := func(remote option.Remote) error {
+		opts := newPullOptions(reg, "hello", "v1", t.TempDir())
+		remote.SetPlainHTTP(false)
+		opts.Remote = remote
+		opts.EventHandler = display.NopHandler{}
+		_, err := RunPull(context.Background(), opts)
+		return err
+	}
+
+	tests := []struct {
+		name    string
+		remote  option.Remote
+		wantErr bool
+	}{
+		{
+			name: "PEM client certificate",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: caPath,
+				CertFilePath:   certPath,
+				KeyFilePath:    keyPath,
+				MinTLSVersion:  "1.2",
+			}},
+		},
+		{
+			name: "PKCS#12 client certificate",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: caPath,
+				PKCS12FilePath: pfxPath,
+				PKCS12Password: "secret",
+			}},
+		},
+		{
+			name: "per registry profile",
+			remote: option.Remote{TLSProfiles: map[string]option.TLSProfile{
+				reg.Host: {
+					CACertFilePath: caPath,
+					CertFilePath:   certPath,
+					KeyFilePath:    keyPath,
+				},
+			}},
+		},
+		{
+			name: "no client certificate",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: caPath,
+			}},
+			wantErr: true,
+		},
+		{
+			name: "untrusted server",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CertFilePath: certPath,
+				KeyFilePath:  keyPath,
+			}},
+			wantErr: true,
+		},
+		{
+			name: "wrong PKCS#12 password",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: caPath,
+				PKCS12FilePath: pfxPath,
+				PKCS12Password: "wrong",
+			}},
+			wantErr: true,
+		},
+		{
+			name: "CA certificate of remote",
+			remote: option.Remote{
+				CACertFilePath: caPath,
+				TLSProfile: option.TLSProfile{
+					CertFilePath: certPath,
+					KeyFilePath:  keyPath,
+				},
+			},
+		},
+		{
+			name: "expired CA certificate",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: expiredPath,
+			}},
+			wantErr: true,
+		},
+		{
+			name: "expired certificate in CA bundle",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: bundlePath,
+				CertFilePath:   certPath,
+				KeyFilePath:    keyPath,
+			}},
+		},
+		{
+			name: "missing CA certificate file",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: filepath.Join(dir, "missing.pem"),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "unsupported TLS version",
+			remote: option.Remote{TLSProfile: option.TLSProfile{
+				CACertFilePath: caPath,
+				MinTLSVersion:  "2.0",
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pull(tt.remote)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+
+	// skipped CA certificates are reported
+	stdout := captureStdout(t, func() {
+		err = pull(option.Remote{TLSProfile: option.TLSProfile{
+			CACertFilePath: bundlePath,
+			CertFilePath:   certPath,
+			KeyFilePath:    keyPath,
+		}})
+	})
+	require.NoError(t, err)
+	assert.Contains(t, stdout, "skipping CA certificate")
+	assert.Contains(t, stdout, `certificate \"CN=expired\"`)
+}
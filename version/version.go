@@ -0,0 +1,47 @@
+// Package version selects tags by semantic version constraints.
+package version
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ErrNotFound is returned when no tag satisfies the constraint.
+var ErrNotFound = errors.New("no tag satisfies the version constraint")
+
+// Select returns the tag of the newest semantic version satisfying the
+// constraint, e.g. `^1.4`, `~1.4.2` or `>=2.0 <3`. Tags such as `v1.4` are
+// treated as `1.4.0`, and tags not in semantic versions are ignored.
+// Prerelease versions are excluded unless includePrerelease is set or the
+// constraint contains a prerelease.
+func Select(tags []string, constraint string, includePrerelease bool) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+	c.IncludePrerelease = includePrerelease
+
+	var selected string
+	var newest *semver.Version
+	for _, tag := range tags {
+		v, err := semver.NewVersion(tag)
+		if err != nil {
+			// not a semantic version
+			continue
+		}
+		if !c.Check(v) {
+			continue
+		}
+		// prefer the greater tag for the same version, e.g. `v1.4.0` over
+		// `1.4`, to be deterministic
+		if newest == nil || v.GreaterThan(newest) || (v.Equal(newest) && tag > selected) {
+			selected, newest = tag, v
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("%w: %q", ErrNotFound, constraint)
+	}
+	return selected, nil
+}
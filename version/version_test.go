@@ -0,0 +1,36 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	tags := []string{"latest", "1.3.9", "v1.4.0", "1.4.2", "1.4", "1.5.0-rc.1", "2.0.0", "2.1.3", "3.0.0-beta", "sha256-abc"}
+	tests := []struct {
+		constraint        string
+		includePrerelease bool
+		want              string
+	}{
+		{constraint: "^1.4", want: "1.4.2"},
+		{constraint: "~1.4.0", want: "1.4.2"},
+		{constraint: "1.4.0", want: "v1.4.0"},
+		{constraint: ">=2.0 <3", want: "2.1.3"},
+		{constraint: ">=2.0, <3", want: "2.1.3"},
+		{constraint: "*", want: "2.1.3"},
+		{constraint: "*", includePrerelease: true, want: "3.0.0-beta"},
+		{constraint: "^1.4", includePrerelease: true, want: "1.5.0-rc.1"},
+		{constraint: ">=1.5.0-rc.0 <1.6", want: "1.5.0-rc.1"},
+	}
+	for _, tt := range tests {
+		got, err := Select(tags, tt.constraint, tt.includePrerelease)
+		assert.Nil(t, err, tt.constraint)
+		assert.Equal(t, tt.want, got, tt.constraint)
+	}
+
+	_, err := Select(tags, ">=4", false)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Select(tags, "not a constraint", false)
+	assert.NotNil(t, err)
+}
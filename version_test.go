@@ -0,0 +1,119 @@
+package artifacts
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/koolay/oras-sdk/option"
+	"github.com/koolay/oras-sdk/orastest"
+	"github.com/koolay/oras-sdk/version"
+)
+
+func TestRunPull_versionConstraint(t *testing.T) {
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	var want digest.Digest
+	for _, tag := range []string{"1.3.0", "1.4.0", "1.4.1", "1.5.0-rc.1", "2.0.0"} {
+		desc := reg.PushArtifact("app", tag, orastest.Artifact{
+			ArtifactType: "application/vnd.test.artifact",
+			Files: []orastest.File{
+				{Name: "version.txt", MediaType: "text/plain", Content: []byte(tag)},
+			},
+		})
+		if tag == "1.4.1" {
+			want = desc.Digest
+		}
+	}
+
+	opts := newPullOptions(reg, "app", "", t.TempDir())
+	opts.VersionConstraint = "^1.4"
+	result, err := RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "1.4.1", result.Tag)
+	assert.Equal(t, "[registry] "+reg.Reference("app", "1.4.1"), result.Reference)
+	assert.Equal(t, want, result.Root.Digest)
+
+	opts.Output = t.TempDir()
+	opts.IncludePrerelease = true
+	result, err = RunPull(context.Background(), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "1.5.0-rc.1", result.Tag)
+
+	opts.VersionConstraint = ">=3"
+	_, err = RunPull(context.Background(), opts)
+	assert.ErrorIs(t, err, version.ErrNotFound)
+
+	opts = newPullOptions(reg, "app", "1.4.0", t.TempDir())
+	opts.VersionConstraint = "^1.4"
+	_, err = RunPull(context.Background(), opts)
+	assert.NotNil(t, err)
+}
+
+func TestVersionConstraint_operations(t *testing.T) {
+	ctx := context.Background()
+	reg := orastest.NewRegistry()
+	defer reg.Close()
+	descs := map[string]ocispec.Descriptor{}
+	for _, tag := range []string{"1.3.0", "1.4.0", "1.4.1", "2.0.0"} {
+		descs[tag] = reg.PushArtifact("app", tag, orastest.Artifact{
+			ArtifactType: "application/vnd.test.artifact",
+			Files: []orastest.File{
+				{Name: "version.txt", MediaType: "text/plain", Content: []byte(tag)},
+			},
+		})
+	}
+	newTarget := func(constraint string) option.Target {
+		return option.Target{
+			Remote:            option.NewRemote(true, "", ""),
+			Type:              option.TargetTypeRemote,
+			RawReference:      reg.Reference("app", ""),
+			VersionConstraint: constraint,
+		}
+	}
+
+	copyOpts := CopyOptions{}
+	copyOpts.From = newTarget("^1.4")
+	copyOpts.To.Type = option.TargetTypeOCILayout
+	copyOpts.To.RawReference = filepath.Join(t.TempDir(), "dst") + ":v1"
+	desc, err := RunCopy(ctx, copyOpts)
+	require.NoError(t, err)
+	assert.Equal(t, descs["1.4.1"].Digest, desc.Digest)
+
+	tagOpts := TagOptions{Tags: []string{"stable"}}
+	tagOpts.Target = newTarget("~1.3")
+	desc, err = RunTag(ctx, tagOpts)
+	require.NoError(t, err)
+	assert.Equal(t, descs["1.3.0"].Digest, desc.Digest)
+
+	deleteOpts := DeleteManifestOptions{}
+	deleteOpts.Target = newTarget("^2")
+	reg.ResetRequests()
+	deleted, err := DeleteManifest(ctx, deleteOpts)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, descs["2.0.0"].Digest, deleted[0].Digest)
+	assert.Contains(t, reg.Requests(), http.MethodDelete+" /v2/app/manifests/"+descs["2.0.0"].Digest.String())
+
+	// blobs are referenced by digest only
+	fetchOpts := FetchBlobOptions{Output: io.Discard}
+	fetchOpts.Target = newTarget("^1.4")
+	_, err = FetchBlob(ctx, fetchOpts)
+	assert.NotNil(t, err)
+	pushOpts := PushBlobOptions{Content: strings.NewReader("hello")}
+	pushOpts.Target = newTarget("^1.4")
+	_, err = PushBlob(ctx, pushOpts)
+	assert.NotNil(t, err)
+	deleteBlobOpts := DeleteBlobOptions{}
+	deleteBlobOpts.Target = newTarget("^1.4")
+	_, err = DeleteBlob(ctx, deleteBlobOpts)
+	assert.NotNil(t, err)
+}